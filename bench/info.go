@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// InfoTimeout is the maximum time the optional info sub-command may run for.
+const InfoTimeout = 30 * time.Second
+
+// Info is the metadata a test implementation describes itself with,
+// returned as JSON by its optional info sub-command.
+type Info struct {
+	// Name and Version identify the test implementation, and
+	// SchedulerVersion is the version of the scheduler under test.
+	Name             string `json:"name"`
+	Version          string `json:"version"`
+	SchedulerVersion string `json:"scheduler_version,omitempty"`
+
+	// RequiredEnv lists the environment variables which must be set for
+	// the benchmark to run.
+	RequiredEnv []string `json:"required_env,omitempty"`
+
+	// Metrics lists the names of the metrics emitted by the status
+	// sub-command.
+	Metrics []string `json:"metrics,omitempty"`
+
+	// ExpectedTasks is the number of tasks expected to be running once the
+	// benchmark completes, if known.
+	ExpectedTasks int `json:"expected_tasks,omitempty"`
+
+	// Validate is whether the implementation supports the validate
+	// sub-command.
+	Validate bool `json:"validate,omitempty"`
+}
+
+// Info invokes the info sub-command of the test implementation. The
+// sub-command is optional, so an implementation which exits non-zero or
+// outputs nothing is assumed not to support it and nil is returned. An
+// error is returned if the output can not be parsed, or ErrInterrupted if
+// the benchmark was interrupted.
+func (r *Runner) Info(ctx context.Context) (*Info, error) {
+	defer r.watch(ctx)()
+
+	log.Println("[DEBUG] runner: executing step 'info'")
+	cmd := newPhaseCmd(r.conf.Path, "info", r.conf.Env, r.conf.Stderr, InfoTimeout)
+	out, err := r.output(cmd)
+	if err == ErrInterrupted {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("[DEBUG] runner: implementation does not support 'info': %v", err)
+		return nil, nil
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		log.Printf("[DEBUG] runner: implementation does not support 'info'")
+		return nil, nil
+	}
+
+	info := &Info{}
+	if err := json.Unmarshal(out, info); err != nil {
+		return nil, fmt.Errorf("failed parsing output of 'info': %v\nStdout: %s", err, out)
+	}
+	return info, nil
+}
+
+// Environ returns the environment the sub-commands of the test
+// implementation are invoked with. Implementations served over HTTP only
+// receive the additional environment of the configuration, while others
+// also inherit the environment of the process.
+func (c *Config) Environ() map[string]string {
+	vars := c.Env
+	if !IsRemote(c.Path) {
+		vars = append(os.Environ(), c.Env...)
+	}
+	env := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	return env
+}
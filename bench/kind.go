@@ -0,0 +1,39 @@
+package bench
+
+import "fmt"
+
+// Kind is the kind of a metric, which determines how its updates are
+// aggregated.
+type Kind string
+
+const (
+	// KindGauge is a value which may rise or fall, such as the number of
+	// running tasks. Each update replaces the previous value. Metrics whose
+	// kind is not given are gauges.
+	KindGauge Kind = "gauge"
+
+	// KindCounter is a cumulative count which only rises, such as the
+	// number of tasks placed. A decrease is treated as the counter having
+	// been reset, so that the count continues from where it was.
+	KindCounter Kind = "counter"
+
+	// KindDelta is an increment of a count, such as 1 for each task which
+	// starts. The metric holds the sum of its increments.
+	KindDelta Kind = "delta"
+
+	// KindSample is a single observation of a distribution, such as the
+	// time a task took to start. Samples are collected in Result.Samples
+	// rather than in the metrics.
+	KindSample Kind = "sample"
+)
+
+// ParseKind parses the kind of a metric, which is a gauge if empty.
+func ParseKind(s string) (Kind, error) {
+	switch kind := Kind(s); kind {
+	case "":
+		return KindGauge, nil
+	case KindGauge, KindCounter, KindDelta, KindSample:
+		return kind, nil
+	}
+	return "", fmt.Errorf("unknown metric kind %q", s)
+}
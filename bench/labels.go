@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// labelReserved holds the characters which may not appear in the names or
+// values of labels.
+const labelReserved = "{}=,|\r\n"
+
+// ParseSeries parses the name of a series, which is the name of a metric
+// optionally followed by the values of its labels, such as
+// "running{class=class_1,node=abc}". Labels are nil for a metric without
+// any.
+func ParseSeries(series string) (string, map[string]string, error) {
+	idx := strings.Index(series, "{")
+	if idx < 0 {
+		if strings.Contains(series, "}") {
+			return "", nil, fmt.Errorf("unbalanced braces in %q", series)
+		}
+		return series, nil, nil
+	}
+	if !strings.HasSuffix(series, "}") {
+		return "", nil, fmt.Errorf("labels of %q must end with '}'", series)
+	}
+
+	metric := series[:idx]
+	labels := make(map[string]string)
+	body := series[idx+1 : len(series)-1]
+	if body == "" {
+		return metric, nil, nil
+	}
+	for _, pair := range strings.Split(body, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		switch {
+		case len(kv) != 2 || kv[0] == "":
+			return "", nil, fmt.Errorf("label %q of %q must be of the form name=value", pair, series)
+		case strings.ContainsAny(kv[0], labelReserved) || strings.ContainsAny(kv[1], labelReserved):
+			return "", nil, fmt.Errorf("label %q of %q contains reserved characters", pair, series)
+		}
+		if _, ok := labels[kv[0]]; ok {
+			return "", nil, fmt.Errorf("label %q of %q is given twice", kv[0], series)
+		}
+		labels[kv[0]] = kv[1]
+	}
+	return metric, labels, nil
+}
+
+// SeriesName returns the name of the series of a metric with the given
+// labels, with the labels sorted so that each series has a single name. It
+// is the name of the metric if there are no labels.
+func SeriesName(metric string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return metric
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(metric)
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(labels[name])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// matchLabels returns whether the series has all of the given values of
+// labels.
+func matchLabels(series string, labels map[string]string) bool {
+	_, have, _ := ParseSeries(series)
+	for name, v := range labels {
+		if l, ok := have[name]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// groupSeries returns the group each of the series falls into when
+// regrouped by only the given labels. Series without labels are left as
+// they are, and take the place of any group of the same name, since they
+// were reported by the implementation rather than derived. The labeled
+// series which fall into such a group are left out.
+func groupSeries(series map[string]struct{}, labels []string) map[string]string {
+	groups := make(map[string]string, len(series))
+	reported := make(map[string]bool)
+	for s := range series {
+		metric, have, err := ParseSeries(s)
+		if err != nil || len(have) == 0 {
+			groups[s] = s
+			reported[s] = true
+			continue
+		}
+		kept := make(map[string]string)
+		for _, name := range labels {
+			if v, ok := have[name]; ok {
+				kept[name] = v
+			}
+		}
+		groups[s] = SeriesName(metric, kept)
+	}
+	for s, group := range groups {
+		if reported[group] && !reported[s] {
+			delete(groups, s)
+		}
+	}
+	return groups
+}
+
+// Filter returns the series of the metrics whose labels have all of the
+// given values. Series without one of the labels are left out.
+func (m Metrics) Filter(labels map[string]string) Metrics {
+	if len(labels) == 0 {
+		return m
+	}
+	keep := make(map[string]bool)
+	out := make(Metrics, len(m))
+	for elapsed, events := range m {
+		for series, value := range events {
+			ok, seen := keep[series]
+			if !seen {
+				ok = matchLabels(series, labels)
+				keep[series] = ok
+			}
+			if !ok {
+				continue
+			}
+			if _, exists := out[elapsed]; !exists {
+				out[elapsed] = make(map[string]float64)
+			}
+			out[elapsed][series] = value
+		}
+	}
+	return out
+}
+
+// Group returns the metrics with each labeled series regrouped by only the
+// given labels, summing the values of the series which fall into the same
+// group. Grouping by no labels gives the total of each metric. The labeled
+// series of a metric are assumed to partition it, so that they can be
+// summed. Series without labels are left as they are, and take the place of
+// any group of the same name.
+func (m Metrics) Group(labels []string) Metrics {
+	// Find the group of each series
+	series := make(map[string]struct{})
+	for _, events := range m {
+		for s := range events {
+			series[s] = struct{}{}
+		}
+	}
+	groups := groupSeries(series, labels)
+	members := make(map[string][]string)
+	for s, group := range groups {
+		members[group] = append(members[group], s)
+	}
+
+	// Sum the last value of each member of a group whenever one changes
+	var times []int64
+	for elapsed := range m {
+		times = append(times, elapsed)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	out := make(Metrics, len(m))
+	last := make(map[string]float64, len(groups))
+	for _, elapsed := range times {
+		changed := make(map[string]struct{})
+		for s, value := range m[elapsed] {
+			group, ok := groups[s]
+			if !ok {
+				continue
+			}
+			last[s] = value
+			changed[group] = struct{}{}
+		}
+		for group := range changed {
+			var sum float64
+			for _, s := range members[group] {
+				sum += last[s]
+			}
+			if _, ok := out[elapsed]; !ok {
+				out[elapsed] = make(map[string]float64)
+			}
+			out[elapsed][group] = sum
+		}
+	}
+	return out
+}
@@ -0,0 +1,127 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSeries(t *testing.T) {
+	cases := []struct {
+		series string
+		metric string
+		labels map[string]string
+	}{
+		{"running", "running", nil},
+		{"running{}", "running", nil},
+		{"running{class=a}", "running", map[string]string{"class": "a"}},
+		{"running{class=a,node=}", "running", map[string]string{"class": "a", "node": ""}},
+		{"running{node=x,class=a}", "running", map[string]string{"class": "a", "node": "x"}},
+		{"{class=a}", "", map[string]string{"class": "a"}},
+	}
+	for _, c := range cases {
+		metric, labels, err := ParseSeries(c.series)
+		if err != nil {
+			t.Fatalf("failed parsing %q: %v", c.series, err)
+		}
+		if metric != c.metric || !reflect.DeepEqual(labels, c.labels) {
+			t.Fatalf("unexpected metric %q and labels %v parsed from %q", metric, labels, c.series)
+		}
+	}
+
+	for _, series := range []string{
+		"running}",
+		"running{class=a",
+		"running{class=a}x",
+		"running{class}",
+		"running{=a}",
+		"running{class=a,}",
+		"running{class=a=b}",
+		"running{class=a{b}",
+		"running{class=a,class=b}",
+	} {
+		if _, _, err := ParseSeries(series); err == nil {
+			t.Fatalf("expected %q to be invalid", series)
+		}
+	}
+}
+
+func TestSeriesName(t *testing.T) {
+	if name := SeriesName("running", nil); name != "running" {
+		t.Fatalf("unexpected name %q of an unlabeled series", name)
+	}
+	labels := map[string]string{"node": "x", "class": "a"}
+	if name := SeriesName("running", labels); name != "running{class=a,node=x}" {
+		t.Fatalf("expected labels to be sorted, got %q", name)
+	}
+}
+
+func TestMetrics_Filter(t *testing.T) {
+	m := Metrics{
+		0: {"running{class=a,node=x}": 1, "running{class=b,node=x}": 2, "running": 3},
+		5: {"running{class=a,node=y}": 4},
+	}
+	out := m.Filter(map[string]string{"class": "a"})
+	expected := Metrics{
+		0: {"running{class=a,node=x}": 1},
+		5: {"running{class=a,node=y}": 4},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("unexpected filtered metrics %v", out)
+	}
+}
+
+func TestMetrics_Group(t *testing.T) {
+	m := Metrics{
+		0:  {"running{class=a,node=x}": 1, "running{class=b,node=x}": 2},
+		5:  {"running{class=a,node=y}": 4},
+		10: {"running{class=a,node=x}": 3, "placed": 7},
+	}
+	cases := []struct {
+		name     string
+		labels   []string
+		expected Metrics
+	}{
+		{
+			// Each group is the sum of the last value of its members
+			"by class", []string{"class"},
+			Metrics{
+				0:  {"running{class=a}": 1, "running{class=b}": 2},
+				5:  {"running{class=a}": 5},
+				10: {"running{class=a}": 7, "placed": 7},
+			},
+		},
+		{
+			"total", nil,
+			Metrics{
+				0:  {"running": 3},
+				5:  {"running": 7},
+				10: {"running": 9, "placed": 7},
+			},
+		},
+		{
+			"unknown label", []string{"region"},
+			Metrics{
+				0:  {"running": 3},
+				5:  {"running": 7},
+				10: {"running": 9, "placed": 7},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if out := m.Group(c.labels); !reflect.DeepEqual(out, c.expected) {
+				t.Fatalf("unexpected grouped metrics %v", out)
+			}
+		})
+	}
+
+	// A series reported without labels takes the place of the group of the
+	// same name
+	m = Metrics{
+		0: {"running{class=a}": 1, "running{class=b}": 2, "running": 5},
+	}
+	expected := Metrics{0: {"running": 5}}
+	if out := m.Group(nil); !reflect.DeepEqual(out, expected) {
+		t.Fatalf("unexpected grouped metrics %v", out)
+	}
+}
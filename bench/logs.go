@@ -0,0 +1,113 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// LogTimeFormat is the layout of the timestamp prefixed to each line of a
+// captured log. It is fixed-width so that the logs line up, and should be
+// used for any other times which are to be correlated with the logs.
+const LogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// lineWriter is an io.WriteCloser which prefixes each line written to it
+// with the time its first byte was written, so that captured output can be
+// correlated against the timeline of the benchmark.
+type lineWriter struct {
+	w    io.WriteCloser
+	buf  []byte
+	lock sync.Mutex
+
+	// lineStart is the time the first byte of the buffered line was
+	// written.
+	lineStart time.Time
+}
+
+// newLineWriter returns a lineWriter writing timestamped lines to w. Closing
+// it flushes any partial line and closes w.
+func newLineWriter(w io.WriteCloser) *lineWriter {
+	return &lineWriter{w: w}
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	if len(l.buf) == 0 {
+		l.lineStart = now
+	}
+	l.buf = append(l.buf, p...)
+	for {
+		idx := bytes.IndexByte(l.buf, '\n')
+		if idx < 0 {
+			break
+		}
+		if err := l.writeLine(l.buf[:idx+1]); err != nil {
+			return 0, err
+		}
+		l.buf = l.buf[idx+1:]
+		l.lineStart = now
+	}
+	return len(p), nil
+}
+
+func (l *lineWriter) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.buf) != 0 {
+		l.writeLine(append(l.buf, '\n'))
+		l.buf = nil
+	}
+	return l.w.Close()
+}
+
+// writeLine writes a single line with the time it started.
+func (l *lineWriter) writeLine(line []byte) error {
+	ts := l.lineStart.UTC().Format(LogTimeFormat)
+	_, err := fmt.Fprintf(l.w, "%s %s", ts, line)
+	return err
+}
+
+// phaseLogs holds the files the stdout and stderr of a step are captured
+// to.
+type phaseLogs struct {
+	stdout *lineWriter
+	stderr *lineWriter
+}
+
+// openPhaseLogs creates the log files of a step within the directory, named
+// after the step, such as "run.stdout.log".
+func openPhaseLogs(dir, phase string) (*phaseLogs, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed creating log directory: %v", err)
+	}
+	stdout, err := os.Create(filepath.Join(dir, phase+".stdout.log"))
+	if err != nil {
+		return nil, fmt.Errorf("failed creating log file: %v", err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, phase+".stderr.log"))
+	if err != nil {
+		stdout.Close()
+		return nil, fmt.Errorf("failed creating log file: %v", err)
+	}
+	return &phaseLogs{
+		stdout: newLineWriter(stdout),
+		stderr: newLineWriter(stderr),
+	}, nil
+}
+
+// Close flushes and closes both log files.
+func (p *phaseLogs) Close() error {
+	err := p.stdout.Close()
+	if serr := p.stderr.Close(); err == nil {
+		err = serr
+	}
+	return err
+}
@@ -0,0 +1,181 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long a sub-command is given to exit after being
+// sent SIGTERM before its process group is forcefully killed.
+const killGracePeriod = 10 * time.Second
+
+// TimeoutError is returned when a step was terminated because it ran for
+// longer than its configured timeout.
+type TimeoutError struct {
+	Phase   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("step '%s' timed out after %s", e.Phase, e.Timeout)
+}
+
+// phaseCmd wraps the invocation of a single sub-command of the test
+// implementation, using the transport the implementation is reached by. It
+// enforces the timeout of the step, if any.
+type phaseCmd struct {
+	// Stdout and Stderr receive the output of the sub-command, and must be
+	// set before it is started.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	proc    process
+	phase   string
+	timeout time.Duration
+
+	// doneCh is closed once the sub-command has exited.
+	doneCh chan struct{}
+
+	// stdoutLog, if set, receives a copy of the sub-command's stdout, and
+	// stdoutTee, if set, receives another to be shown on the terminal.
+	stdoutLog io.Writer
+	stdoutTee io.Writer
+
+	// started and exited are the times the sub-command was started and
+	// was reaped. They are zero if it never reached that point.
+	started time.Time
+	exited  time.Time
+
+	timedOut     bool
+	timedOutLock sync.Mutex
+}
+
+// newPhaseCmd creates a phaseCmd which will invoke the given sub-command of
+// the test implementation at path, using the transport matching the path.
+// The variables in env are added to the environment of the sub-command, and
+// its stderr is written to stderr. A zero timeout disables the deadline. The
+// sub-command is told the status formats the runner accepts, unless env
+// overrides it.
+func newPhaseCmd(path, phase string, env []string, stderr io.Writer, timeout time.Duration) *phaseCmd {
+	env = append([]string{StatusFormatEnv + "=" + StatusFormatJSON}, env...)
+	return &phaseCmd{
+		Stderr:  stderr,
+		proc:    newProcess(path, phase, env, stderr),
+		phase:   phase,
+		timeout: timeout,
+		doneCh:  make(chan struct{}),
+	}
+}
+
+// StdoutPipe returns a pipe connected to the stdout of the sub-command once
+// it starts. The pipe must be fully read before calling Wait.
+func (c *phaseCmd) StdoutPipe() (io.ReadCloser, error) {
+	if c.Stdout != nil {
+		return nil, fmt.Errorf("stdout already set")
+	}
+	return c.proc.stdoutPipe()
+}
+
+// StatusPipe returns a pipe connected to the dedicated status channel of the
+// sub-command once it starts, or nil if its transport has none. The pipe
+// must be fully read before calling Wait, and closed by the caller.
+func (c *phaseCmd) StatusPipe() (io.ReadCloser, error) {
+	return c.proc.statusPipe()
+}
+
+// Start starts the sub-command and arms the timeout, if any.
+func (c *phaseCmd) Start() error {
+	if err := c.proc.start(c.Stdout, c.Stderr); err != nil {
+		return err
+	}
+	c.started = time.Now()
+	if c.timeout > 0 {
+		go c.enforceTimeout()
+	}
+	return nil
+}
+
+// Wait waits for the sub-command to exit. If the sub-command was killed
+// because it exceeded its timeout, a *TimeoutError is returned.
+func (c *phaseCmd) Wait() error {
+	err := c.proc.wait()
+	c.exited = time.Now()
+	close(c.doneCh)
+
+	if c.didTimeOut() {
+		return &TimeoutError{Phase: c.phase, Timeout: c.timeout}
+	}
+	return err
+}
+
+// didTimeOut returns whether the sub-command was terminated because it
+// exceeded its timeout.
+func (c *phaseCmd) didTimeOut() bool {
+	c.timedOutLock.Lock()
+	defer c.timedOutLock.Unlock()
+	return c.timedOut
+}
+
+// signal delivers the signal to the sub-command.
+func (c *phaseCmd) signal(sig syscall.Signal) error {
+	return c.proc.signal(sig)
+}
+
+// exitCode returns the exit code of the sub-command once it has been
+// reaped, which is -1 if it was killed or never exited.
+func (c *phaseCmd) exitCode() int {
+	return c.proc.exitCode()
+}
+
+// step returns the record of how the sub-command executed.
+func (c *phaseCmd) step() *Step {
+	return &Step{
+		Phase:    c.phase,
+		Start:    c.started,
+		End:      c.exited,
+		ExitCode: c.exitCode(),
+		TimedOut: c.didTimeOut(),
+	}
+}
+
+// terminate sends SIGTERM to the sub-command, escalating to SIGKILL if it
+// has not exited after the grace period. Callers must still call Wait to
+// reap the sub-command.
+func (c *phaseCmd) terminate() {
+	if err := c.signal(syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		log.Printf("[ERR] runner: failed sending SIGTERM to step '%s': %v", c.phase, err)
+	}
+
+	go func() {
+		select {
+		case <-c.doneCh:
+		case <-time.After(killGracePeriod):
+			log.Printf("[WARN] runner: step '%s' did not exit after %s; sending SIGKILL",
+				c.phase, killGracePeriod)
+			if err := c.signal(syscall.SIGKILL); err != nil {
+				log.Printf("[ERR] runner: failed sending SIGKILL to step '%s': %v", c.phase, err)
+			}
+		}
+	}()
+}
+
+// enforceTimeout waits for the timeout to elapse and terminates the
+// sub-command if it has not exited by then.
+func (c *phaseCmd) enforceTimeout() {
+	select {
+	case <-c.doneCh:
+		return
+	case <-time.After(c.timeout):
+	}
+
+	c.timedOutLock.Lock()
+	c.timedOut = true
+	c.timedOutLock.Unlock()
+
+	log.Printf("[ERR] runner: step '%s' exceeded timeout of %s; terminating", c.phase, c.timeout)
+	c.terminate()
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+// +build !windows
+
+package bench
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup configures the command to start in a new process group.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signalProcessGroup delivers the signal to every process in the process
+// group led by p.
+func signalProcessGroup(p *os.Process, sig syscall.Signal) error {
+	return syscall.Kill(-p.Pid, sig)
+}
+
+// statusChannel passes a pipe to the command as an additional file
+// descriptor, named in StatusFDEnv, for it to output status on. Returns the
+// read and write ends of the pipe. The write end must be closed once the
+// command has started. The channel is only closed once every process which
+// inherited it exits or closes it, including any the command starts.
+func statusChannel(cmd *exec.Cmd) (*os.File, *os.File, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed creating status channel: %v", err)
+	}
+	cmd.ExtraFiles = append(cmd.ExtraFiles, w)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	fd := 2 + len(cmd.ExtraFiles)
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", StatusFDEnv, fd))
+	return r, w, nil
+}
@@ -0,0 +1,25 @@
+//go:build windows
+// +build windows
+
+package bench
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup is a no-op on Windows, which has no process groups.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup kills the process. Windows does not support sending
+// arbitrary signals, so the signal is ignored.
+func signalProcessGroup(p *os.Process, sig syscall.Signal) error {
+	return p.Kill()
+}
+
+// statusChannel returns no pipe on Windows, which does not support passing
+// additional file descriptors, so status is only read from stdout.
+func statusChannel(cmd *exec.Cmd) (*os.File, *os.File, error) {
+	return nil, nil, nil
+}
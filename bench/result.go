@@ -0,0 +1,105 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// Result holds the outcome of a single execution of a benchmark.
+type Result struct {
+	// Metrics is the time-indexed status information collected. It is nil
+	// if the status step was never started.
+	Metrics Metrics
+
+	// Samples holds the observations of the metrics of KindSample. It is
+	// nil if the status step was never started.
+	Samples Samples
+
+	// Units holds the first unit given for each metric which has one, keyed
+	// by the name of the metric, and Events holds the events emitted by the
+	// implementation, in time order.
+	Units  map[string]string
+	Events []*Event
+
+	// Err is the first failure of the setup, status or run steps, and
+	// TeardownErr is the failure of the teardown step.
+	Err         error
+	TeardownErr error
+
+	// Validation is the output of the validate step, and Invalid is its
+	// failure, meaning the metrics can not be trusted.
+	Validation []byte
+	Invalid    error
+
+	// Interrupted is the signal which interrupted the benchmark, if any.
+	Interrupted os.Signal
+
+	// Origin is the time from which elapsed time is measured.
+	Origin time.Time
+
+	// Steps holds every step which was started, in the order they were
+	// started. Timeline holds the start and end of each step, and
+	// Durations holds the time taken by each step which completed, in
+	// milliseconds, keyed by metric names such as "run_duration_ms".
+	Steps     []*Step
+	Timeline  []TimelineEvent
+	Durations map[string]float64
+}
+
+// Complete returns whether every step up to teardown succeeded, meaning the
+// collected metrics cover the entire benchmark and were found to be valid.
+func (r *Result) Complete() bool {
+	return r.Err == nil && r.Interrupted == nil && r.Invalid == nil
+}
+
+// TimedOut returns whether the benchmark failed because a step exceeded its
+// timeout.
+func (r *Result) TimedOut() bool {
+	if serr, ok := r.Err.(*StepError); ok {
+		_, ok := serr.Err.(*TimeoutError)
+		return ok
+	}
+	return false
+}
+
+// Step records how a step of the benchmark executed.
+type Step struct {
+	Phase string
+
+	// Start and End are the times the step was started and exited. End is
+	// zero if the step never exited.
+	Start time.Time
+	End   time.Time
+
+	// ExitCode is the exit code of the step, which is -1 if it was killed
+	// by a signal or its connection was lost.
+	ExitCode int
+	TimedOut bool
+}
+
+// Duration returns how long the step ran for.
+func (s *Step) Duration() time.Duration {
+	if s.End.IsZero() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// StepError is returned when a step of the benchmark fails.
+type StepError struct {
+	Phase  string
+	Err    error
+	Stdout []byte
+}
+
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("step '%s' failed: %v", e.Phase, e.Err)
+	if _, ok := e.Err.(*TimeoutError); ok {
+		msg = e.Err.Error()
+	}
+	if len(e.Stdout) == 0 {
+		return msg
+	}
+	return fmt.Sprintf("%s\nStdout: %s", msg, e.Stdout)
+}
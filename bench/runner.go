@@ -0,0 +1,440 @@
+// Package bench executes the benchmark of a scheduler by driving its test
+// implementation through each step of the benchmark lifecycle, collecting
+// the status updates it emits along the way. It is the library behind the
+// bench-runner command, and allows benchmarks to be embedded in other
+// tooling:
+//
+//	r := bench.New(&bench.Config{
+//		Path: "bin/bench-nomad",
+//		Env:  []string{"NUM_JOBS=100"},
+//	})
+//	res := r.Run(context.Background())
+//	if !res.Complete() {
+//		log.Fatalf("benchmark failed: %v", res.Err)
+//	}
+//
+// Implementations served as Go plugins are launched once and shared by every
+// Runner with the same path and environment, so that they outlive any one
+// Runner. Programs which run them must call ClosePlugins before exiting, or
+// the plugins are left running.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Timeouts holds the maximum amount of time each step of the benchmark may
+// run for. A zero value means the step may run indefinitely.
+type Timeouts struct {
+	Setup    time.Duration
+	Run      time.Duration
+	Status   time.Duration
+	Validate time.Duration
+	Teardown time.Duration
+}
+
+// Config holds the options controlling how a benchmark is executed.
+type Config struct {
+	// Path is the path of the executable of the test implementation, the
+	// http:// or https:// URL of an implementation served over HTTP, or
+	// the path of a Go plugin prefixed with PluginPrefix. Plugins are left
+	// running until ClosePlugins is called.
+	Path     string
+	Timeouts Timeouts
+
+	// Env holds additional environment variables, in the form "key=value",
+	// passed to each sub-command of the test implementation.
+	Env []string
+
+	// Origin is the step from which elapsed time is measured, either
+	// OriginRun or OriginStatus. Defaults to OriginRun.
+	Origin string
+
+	// Validate is whether the validate step is executed once the status
+	// step completes.
+	Validate bool
+
+	// LogDir, if set, is the directory the stdout and stderr of each step
+	// are captured to, in files named after the step such as
+	// "run.stderr.log".
+	LogDir string
+
+	// Stderr receives the stderr of every step. Defaults to os.Stderr.
+	Stderr io.Writer
+
+	// Stdout receives a copy of the stdout of every step of the benchmark
+	// other than status, whose stdout is its status output. Defaults to
+	// os.Stdout.
+	Stdout io.Writer
+
+	// Supervisor tracks the steps being executed. It may be shared by
+	// several Runners so that interrupting it stops all of them. If nil,
+	// the Runner creates its own.
+	Supervisor *Supervisor
+
+	// OnStepStart and OnStepEnd, if set, are called as each step starts
+	// and exits.
+	OnStepStart func(phase string)
+	OnStepEnd   func(step *Step)
+
+	// OnUpdate, if set, is called with each status update as it is
+	// received. It is called from its own goroutine, and must not block.
+	OnUpdate func(update *Update)
+
+	// BeforeTeardown, if set, is called with the result of the benchmark
+	// before the teardown step is executed, so that the result may be
+	// saved without waiting on a teardown which may take a long time. The
+	// teardown error, steps, timeline and durations are not yet set.
+	BeforeTeardown func(res *Result)
+}
+
+// Runner executes the benchmark of a single test implementation. It
+// guarantees that teardown is executed exactly once per execution,
+// regardless of how the other steps completed.
+type Runner struct {
+	conf Config
+	sup  *Supervisor
+}
+
+// New creates a Runner for the test implementation described by the config.
+func New(conf *Config) *Runner {
+	c := *conf
+	if c.Origin == "" {
+		c.Origin = OriginRun
+	}
+	if c.Stderr == nil {
+		c.Stderr = os.Stderr
+	}
+	if c.Stdout == nil {
+		c.Stdout = os.Stdout
+	}
+	sup := c.Supervisor
+	if sup == nil {
+		sup = NewSupervisor()
+	}
+	return &Runner{conf: c, sup: sup}
+}
+
+// Supervisor returns the Supervisor tracking the steps of the Runner.
+func (r *Runner) Supervisor() *Supervisor {
+	return r.sup
+}
+
+// Run executes every step of the benchmark, followed by the teardown. If
+// the context is cancelled, the benchmark is interrupted as if by SIGTERM,
+// though teardown is still executed.
+func (r *Runner) Run(ctx context.Context) *Result {
+	defer r.watch(ctx)()
+	e := &execution{Runner: r}
+	return e.run()
+}
+
+// Exec executes a single step of the benchmark on its own, writing its
+// stdout to stdout, which may be nil to discard it. Cancelling the context
+// terminates the step. The step is returned if it was started, even if it
+// failed.
+func (r *Runner) Exec(ctx context.Context, phase string, timeout time.Duration, stdout io.Writer) (*Step, error) {
+	cmd := newPhaseCmd(r.conf.Path, phase, r.conf.Env, r.conf.Stderr, timeout)
+	cmd.Stdout = stdout
+	return r.exec(ctx, cmd, nil)
+}
+
+// ExecStatus executes the status step on its own, as with Exec, writing
+// each line of its status output to status. The status is read from the
+// dedicated status channel instead of stdout once the implementation uses
+// it, as when the benchmark is run.
+func (r *Runner) ExecStatus(ctx context.Context, timeout time.Duration, status io.Writer) (*Step, error) {
+	cmd := newPhaseCmd(r.conf.Path, "status", r.conf.Env, r.conf.Stderr, timeout)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed attaching stdout: %v", err)
+	}
+	channel, err := cmd.StatusPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed attaching status channel: %v", err)
+	}
+	if channel != nil {
+		defer channel.Close()
+	}
+	return r.exec(ctx, cmd, func() {
+		scanStatus(stdout, channel, func(line string, _ time.Time) {
+			io.WriteString(status, line+"\n")
+		})
+	})
+}
+
+// exec starts the sub-command and waits for it to exit, terminating it if
+// the context is cancelled. If collect is given, it is called once the
+// sub-command starts, and must read its output to completion.
+func (r *Runner) exec(ctx context.Context, cmd *phaseCmd, collect func()) (*Step, error) {
+	if err := r.start(cmd); err != nil {
+		return nil, err
+	}
+
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.terminate()
+		case <-stopCh:
+		}
+	}()
+	if collect != nil {
+		collect()
+	}
+	err := r.wait(cmd)
+	close(stopCh)
+	return cmd.step(), err
+}
+
+// watch interrupts the benchmark once the context is cancelled, until the
+// returned function is called.
+func (r *Runner) watch(ctx context.Context) func() {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	if ctx.Err() != nil {
+		r.sup.Interrupt(syscall.SIGTERM)
+		return func() {}
+	}
+
+	stopCh := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.sup.Interrupt(syscall.SIGTERM)
+		case <-stopCh:
+		}
+	}()
+	return func() { close(stopCh) }
+}
+
+// start starts the sub-command of a step through the supervisor.
+func (r *Runner) start(cmd *phaseCmd) error {
+	if err := r.sup.start(cmd); err != nil {
+		return err
+	}
+	if r.conf.OnStepStart != nil {
+		r.conf.OnStepStart(cmd.phase)
+	}
+	return nil
+}
+
+// wait waits for the sub-command of a step to exit.
+func (r *Runner) wait(cmd *phaseCmd) error {
+	err := r.sup.wait(cmd)
+	if r.conf.OnStepEnd != nil {
+		r.conf.OnStepEnd(cmd.step())
+	}
+	return err
+}
+
+// output runs the sub-command of a step to completion and returns its
+// stdout, which is also copied to the log and terminal if set.
+func (r *Runner) output(cmd *phaseCmd) ([]byte, error) {
+	var stdout bytes.Buffer
+	writers := []io.Writer{&stdout}
+	for _, w := range []io.Writer{cmd.stdoutLog, cmd.stdoutTee} {
+		if w != nil {
+			writers = append(writers, w)
+		}
+	}
+	cmd.Stdout = io.MultiWriter(writers...)
+	if err := r.start(cmd); err != nil {
+		return nil, err
+	}
+	err := r.wait(cmd)
+	return stdout.Bytes(), err
+}
+
+// execution holds the state of a single execution of the benchmark.
+type execution struct {
+	*Runner
+
+	// cmds holds the sub-command of every step in the order they were
+	// created, and origin is the time from which elapsed time is measured.
+	cmds   []*phaseCmd
+	origin time.Time
+
+	// logs holds the files the output of each step is captured to.
+	logs []*phaseLogs
+
+	teardownOnce sync.Once
+	teardownErr  error
+}
+
+// run executes every step of the benchmark, followed by the teardown.
+func (e *execution) run() *Result {
+	res := &Result{}
+	res.Err = e.execute(res)
+	if res.Err != nil {
+		log.Printf("[ERR] runner: %v", res.Err)
+	}
+	res.Interrupted = e.sup.Interrupted()
+
+	// Check the correctness of a completed benchmark before tearing it down
+	if res.Complete() && e.conf.Validate {
+		res.Validation, res.Invalid = e.validate()
+		if res.Invalid != nil {
+			log.Printf("[ERR] runner: benchmark is invalid: %v", res.Invalid)
+		}
+		res.Interrupted = e.sup.Interrupted()
+	}
+
+	if e.conf.BeforeTeardown != nil {
+		e.conf.BeforeTeardown(res)
+	}
+
+	// Always run the teardown
+	if res.TeardownErr = e.teardown(); res.TeardownErr != nil {
+		log.Printf("[ERR] runner: %v", res.TeardownErr)
+	}
+	if res.Interrupted != nil {
+		log.Printf("[ERR] runner: benchmark interrupted by signal: %v", res.Interrupted)
+	}
+
+	for _, logs := range e.logs {
+		if err := logs.Close(); err != nil {
+			log.Printf("[ERR] runner: failed writing captured output: %v", err)
+		}
+	}
+
+	// Record when each step started and ended, now that all have exited
+	for _, cmd := range e.cmds {
+		if !cmd.started.IsZero() {
+			res.Steps = append(res.Steps, cmd.step())
+		}
+	}
+	if e.origin.IsZero() && len(res.Steps) != 0 {
+		e.origin = res.Steps[0].Start
+	}
+	res.Origin = e.origin
+	res.Timeline = timeline(res.Steps, e.origin)
+	res.Durations = phaseDurations(res.Steps)
+	return res
+}
+
+// command creates the sub-command for a step of the benchmark, tracking it
+// so that it is recorded in the timeline. Its stdout is shown on the
+// terminal and, if enabled, the output of the step is also captured to log
+// files.
+func (e *execution) command(phase string, timeout time.Duration) *phaseCmd {
+	cmd := newPhaseCmd(e.conf.Path, phase, e.conf.Env, e.conf.Stderr, timeout)
+	cmd.stdoutTee = e.conf.Stdout
+	e.cmds = append(e.cmds, cmd)
+	if e.conf.LogDir == "" {
+		return cmd
+	}
+
+	logs, err := openPhaseLogs(e.conf.LogDir, phase)
+	if err != nil {
+		log.Printf("[ERR] runner: failed capturing output of step '%s': %v", phase, err)
+		return cmd
+	}
+	e.logs = append(e.logs, logs)
+	cmd.Stderr = io.MultiWriter(cmd.Stderr, logs.stderr)
+	cmd.stdoutLog = logs.stdout
+	return cmd
+}
+
+// execute runs the setup, status and run steps, stopping at the first
+// failure. Once the status step has started, the status it collected is
+// recorded in the result even if a later step fails.
+func (e *execution) execute(res *Result) error {
+	// Perform setup
+	log.Println("[DEBUG] runner: executing step 'setup'")
+	setupCmd := e.command("setup", e.conf.Timeouts.Setup)
+	if out, err := e.output(setupCmd); err != nil {
+		return &StepError{Phase: "setup", Err: err, Stdout: out}
+	}
+
+	// Start running the status collector
+	log.Println("[DEBUG] runner: executing step 'status'")
+	statusCmd := e.command("status", e.conf.Timeouts.Status)
+	outBuf, err := statusCmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed attaching stdout: %v", err)
+	}
+	chanBuf, err := statusCmd.StatusPipe()
+	if err != nil {
+		return fmt.Errorf("failed attaching status channel: %v", err)
+	}
+	if chanBuf != nil {
+		defer chanBuf.Close()
+	}
+	if err := e.start(statusCmd); err != nil {
+		return &StepError{Phase: "status", Err: err}
+	}
+
+	// Start listening for updates
+	var outStream io.Reader = outBuf
+	if statusCmd.stdoutLog != nil {
+		outStream = io.TeeReader(outBuf, statusCmd.stdoutLog)
+	}
+	srv := newStatusServer(outStream, chanBuf, e.conf.OnUpdate)
+	go srv.run()
+
+	// Start running the benchmark
+	log.Println("[DEBUG] runner: executing step 'run'")
+	runCmd := e.command("run", e.conf.Timeouts.Run)
+	if out, err := e.output(runCmd); err != nil {
+		// Stop collecting status, since the benchmark will never complete.
+		// The output must be drained before the status command is reaped.
+		statusCmd.terminate()
+		srv.wait(e.startOrigin(statusCmd, runCmd), res)
+		e.wait(statusCmd)
+		return &StepError{Phase: "run", Err: err, Stdout: out}
+	}
+
+	// Wait for the status command to return
+	log.Println("[DEBUG] runner: waiting for step 'status' to complete...")
+	srv.wait(e.startOrigin(statusCmd, runCmd), res)
+	if err := e.wait(statusCmd); err != nil {
+		return &StepError{Phase: "status", Err: err}
+	}
+	return nil
+}
+
+// startOrigin determines the origin of the timeline of the benchmark, which
+// is the start of either the status or run step as configured. The start of
+// the status step is used if the run step was never started.
+func (e *execution) startOrigin(statusCmd, runCmd *phaseCmd) time.Time {
+	e.origin = statusCmd.started
+	if e.conf.Origin != OriginStatus && !runCmd.started.IsZero() {
+		e.origin = runCmd.started
+	}
+	return e.origin
+}
+
+// validate executes the validate step, returning its output. An error is
+// returned if the implementation found the benchmark to be invalid.
+func (e *execution) validate() ([]byte, error) {
+	log.Println("[DEBUG] runner: executing step 'validate'")
+	validateCmd := e.command("validate", e.conf.Timeouts.Validate)
+	out, err := e.output(validateCmd)
+	if err != nil {
+		return out, &StepError{Phase: "validate", Err: err, Stdout: out}
+	}
+	return out, nil
+}
+
+// teardown executes the teardown step. It is safe to call multiple times,
+// but the sub-command is only ever invoked once.
+func (e *execution) teardown() error {
+	e.teardownOnce.Do(func() {
+		log.Println("[DEBUG] runner: executing step 'teardown'")
+		teardownCmd := e.command("teardown", e.conf.Timeouts.Teardown)
+		if out, err := e.output(teardownCmd); err != nil {
+			e.teardownErr = &StepError{Phase: "teardown", Err: err, Stdout: out}
+		}
+	})
+	return e.teardownErr
+}
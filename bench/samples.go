@@ -0,0 +1,51 @@
+package bench
+
+import "sort"
+
+// Sample is a single observation of a metric of KindSample, at the elapsed
+// time in milliseconds at which it was reported.
+type Sample struct {
+	Elapsed int64
+	Value   float64
+}
+
+// Samples holds the observations of the metrics of KindSample, in time
+// order, indexed by the name of their series as returned by SeriesName.
+type Samples map[string][]Sample
+
+// Filter returns the series of the samples whose labels have all of the
+// given values. Series without one of the labels are left out.
+func (s Samples) Filter(labels map[string]string) Samples {
+	if len(labels) == 0 {
+		return s
+	}
+	out := make(Samples, len(s))
+	for series, samples := range s {
+		if matchLabels(series, labels) {
+			out[series] = samples
+		}
+	}
+	return out
+}
+
+// Group returns the samples with each labeled series regrouped by only the
+// given labels, merging the observations of the series which fall into the
+// same group. Grouping by no labels gives every observation of each metric.
+// Series without labels are left as they are, and take the place of any
+// group of the same name.
+func (s Samples) Group(labels []string) Samples {
+	series := make(map[string]struct{}, len(s))
+	for name := range s {
+		series[name] = struct{}{}
+	}
+	out := make(Samples)
+	for name, group := range groupSeries(series, labels) {
+		out[group] = append(out[group], s[name]...)
+	}
+	for _, samples := range out {
+		sort.SliceStable(samples, func(i, j int) bool {
+			return samples[i].Elapsed < samples[j].Elapsed
+		})
+	}
+	return out
+}
@@ -0,0 +1,343 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics holds the values of the metrics of a benchmark, indexed by the
+// elapsed time in milliseconds at which they were reported, and then by the
+// name of the series, which includes the labels of the metric if it has any,
+// as returned by SeriesName.
+type Metrics map[int64]map[string]float64
+
+// Update is a single status update emitted by a test implementation.
+type Update struct {
+	// Metric is the name of the metric, and Value its new value.
+	Metric string
+	Value  float64
+
+	// Labels holds the dimensions of the metric, such as the node class
+	// the tasks are running on. It is nil if the metric has none.
+	Labels map[string]string
+
+	// Kind is the kind of the metric, which determines how its updates are
+	// aggregated, and Unit is the unit of its values, if given.
+	Kind Kind
+	Unit string
+
+	// Time is when the update happened, as given by the implementation,
+	// or otherwise when the runner received it.
+	Time time.Time
+}
+
+// statusServer is responsible for consuming status information which is
+// output by a test implementation.
+type statusServer struct {
+	// The output streams. These are attached to the stdout of the test
+	// implementation and, if it has one, its dedicated status channel, and
+	// are used to scan line-by-line over them.
+	stdout  io.Reader
+	channel io.Reader
+
+	// Simple metrics about the status collector. Used to print some basic
+	// debugging information to stdout.
+	lastUpdate        time.Time
+	totalUpdates      int
+	updateMetricsLock sync.Mutex
+	logInterval       time.Duration
+
+	// The updateCh is used to pass status data from the scanner to the
+	// result collector. It is closed once the output stream is exhausted.
+	updateCh chan *Update
+
+	// The doneCh is closed once every update has been collected.
+	doneCh  chan struct{}
+	updates []*Update
+
+	// events holds the events in the status output. They are collected as
+	// they are scanned, so are complete once the doneCh is closed.
+	events []*Event
+
+	// onUpdate, if set, is called with each update as it is collected.
+	onUpdate func(*Update)
+}
+
+// newStatusServer makes a new statusServer and initializes the fields. The
+// channel may be nil if the test implementation has no dedicated status
+// channel.
+func newStatusServer(stdout, channel io.Reader, onUpdate func(*Update)) *statusServer {
+	return &statusServer{
+		stdout:      stdout,
+		channel:     channel,
+		updateCh:    make(chan *Update, 512),
+		doneCh:      make(chan struct{}),
+		onUpdate:    onUpdate,
+		logInterval: statusLogInterval,
+	}
+}
+
+// run is the main loop of the status server which is responsible for
+// scanning lines of output from a test, parsing it into a status update,
+// and sending it down to the update handler. Returns once the output
+// streams are exhausted.
+func (s *statusServer) run() {
+	// Start the update parser
+	defer close(s.updateCh)
+	go s.handleUpdates()
+	go s.logUpdateTimes()
+
+	scanStatus(s.stdout, s.channel, func(line string, received time.Time) {
+		update, event, err := ParseStatusLine(line, received)
+		if err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			return
+		}
+		if event != nil {
+			s.events = append(s.events, event)
+			return
+		}
+
+		// Send the update
+		s.updateCh <- update
+	})
+}
+
+// ParseStatusUpdate parses a single line of status output in the text
+// format, "<metric>|<value>[|<timestamp>[|<kind>]]". The metric may be
+// followed by labels, as in "running{class=class_1}". If the timestamp is
+// not given, or is empty, the update is timestamped with now. If the kind
+// is not given, the metric is a gauge.
+func ParseStatusUpdate(payload string, now time.Time) (*Update, error) {
+	// Used to parse and store a timestamp and kind if given
+	ts := now.UnixNano()
+	kind := KindGauge
+	var err error
+
+	// Parse the payload parts
+	parts := strings.Split(payload, "|")
+	switch len(parts) {
+	case 2:
+		// Missing timestamp (will auto-generate)
+	case 3, 4:
+		// Timestamp present unless empty, parse and use
+		if len(parts) == 4 {
+			if kind, err = ParseKind(parts[3]); err != nil {
+				return nil, fmt.Errorf("failed parsing metric kind in %q: %v", payload, err)
+			}
+		}
+		if parts[2] == "" {
+			break
+		}
+		ts, err = strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing metric timestamp in %q: %v", payload, err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid metric payload: %q", payload)
+	}
+
+	// Parse the metric
+	metric, labels, err := ParseSeries(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing metric labels in %q: %v", payload, err)
+	}
+	val, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing metric value in %q: %v", payload, err)
+	}
+
+	return &Update{
+		Metric: metric,
+		Value:  val,
+		Labels: labels,
+		Kind:   kind,
+		Time:   time.Unix(0, ts),
+	}, nil
+}
+
+// wait blocks until the output stream has been exhausted and every update
+// has been collected, and then records the time-indexed metrics in the
+// result, along with the samples of metrics of KindSample, the units of the
+// metrics and the events. The elapsed time of each update is measured from
+// the origin, so updates which precede it have a negative elapsed time.
+// Updates are aggregated in time order according to the kind of their
+// metric.
+func (s *statusServer) wait(origin time.Time, res *Result) {
+	<-s.doneCh
+	updates := make([]*Update, len(s.updates))
+	copy(updates, s.updates)
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].Time.Before(updates[j].Time)
+	})
+
+	// Used to store events and times, along with whether each series of
+	// running tasks was reported by the origin.
+	metrics := make(Metrics)
+	samples := make(Samples)
+	units := make(map[string]string)
+	running := make(map[string]bool)
+	agg := newAggregator()
+	start := origin.UnixNano()
+	for _, update := range updates {
+		// Compute elapsed time and log the value away. We reduce to
+		// milliseconds here so that our map keys are guaranteed unique
+		// per millisecond. We otherwise could have a rounding problem.
+		elapsed := (update.Time.UnixNano() - start) / int64(time.Millisecond)
+		series := SeriesName(update.Metric, update.Labels)
+		if _, ok := units[update.Metric]; !ok && update.Unit != "" {
+			units[update.Metric] = update.Unit
+		}
+		value, ok := agg.add(series, update)
+		switch {
+		case !ok:
+			continue
+		case update.Kind == KindSample:
+			samples[series] = append(samples[series], Sample{Elapsed: elapsed, Value: value})
+			continue
+		}
+		if _, ok := metrics[elapsed]; !ok {
+			metrics[elapsed] = make(map[string]float64)
+		}
+		metrics[elapsed][series] = value
+		if update.Metric == "running" {
+			running[series] = running[series] || elapsed <= 0
+		}
+	}
+
+	// Start the clock with 0 running in each series, unless the number of
+	// running tasks was already reported by then.
+	if len(running) == 0 {
+		running["running"] = false
+	}
+	for series, reported := range running {
+		if reported {
+			continue
+		}
+		if _, ok := metrics[0]; !ok {
+			metrics[0] = make(map[string]float64)
+		}
+		metrics[0][series] = 0
+	}
+
+	// Place the events on the same timeline
+	sort.SliceStable(s.events, func(i, j int) bool {
+		return s.events[i].Time.Before(s.events[j].Time)
+	})
+	for _, event := range s.events {
+		event.Elapsed = (event.Time.UnixNano() - start) / int64(time.Millisecond)
+	}
+
+	res.Metrics, res.Samples, res.Events = metrics, samples, s.events
+	if len(units) != 0 {
+		res.Units = units
+	}
+}
+
+// aggregator folds the updates of each series into its value, according
+// to the kind of its metric.
+type aggregator struct {
+	// kinds is the kind each series was first reported as, and conflicts
+	// holds the series which were then reported as another kind.
+	kinds     map[string]Kind
+	conflicts map[string]bool
+
+	// last is the last value reported for each series, and offset is added
+	// to the values of a counter to account for it being reset.
+	last   map[string]float64
+	offset map[string]float64
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		kinds:     make(map[string]Kind),
+		conflicts: make(map[string]bool),
+		last:      make(map[string]float64),
+		offset:    make(map[string]float64),
+	}
+}
+
+// add aggregates the update of the series, returning the new value of the
+// series. The update is ignored, returning false, if the series was already
+// reported as a different kind.
+func (a *aggregator) add(series string, update *Update) (float64, bool) {
+	kind, ok := a.kinds[series]
+	if !ok {
+		a.kinds[series] = update.Kind
+	} else if kind != update.Kind {
+		if !a.conflicts[series] {
+			log.Printf("[WARN] runner: ignoring updates of %q as a %s, since it was first reported as a %s",
+				series, update.Kind, kind)
+			a.conflicts[series] = true
+		}
+		return 0, false
+	}
+
+	value := update.Value
+	switch update.Kind {
+	case KindCounter:
+		if last, ok := a.last[series]; ok && value < last {
+			a.offset[series] += last
+		}
+		a.last[series] = value
+		value += a.offset[series]
+	case KindDelta:
+		value += a.last[series]
+		a.last[series] = value
+	}
+	return value, true
+}
+
+// handleUpdates is used to read updates off of the updateCh and collect them
+// in the order they were received. Blocks until the updateCh is closed, and
+// then closes the doneCh.
+func (s *statusServer) handleUpdates() {
+	defer close(s.doneCh)
+
+	for update := range s.updateCh {
+		s.updates = append(s.updates, update)
+		if s.onUpdate != nil {
+			s.onUpdate(update)
+		}
+
+		// Refresh the last update time
+		s.updateMetricsLock.Lock()
+		s.lastUpdate = time.Now()
+		s.totalUpdates++
+		s.updateMetricsLock.Unlock()
+	}
+}
+
+// statusLogInterval is how often the last time an update was received is
+// logged while status is collected.
+var statusLogInterval = 10 * time.Second
+
+// logUpdateTimes periodically logs the last time we saw an update from the
+// status collector. This is helpful when debugging so that we know if the
+// sub-command has halted for some reason and is no longer fetching status.
+func (s *statusServer) logUpdateTimes() {
+	for {
+		select {
+		case <-time.After(s.logInterval):
+			s.updateMetricsLock.Lock()
+			last := s.lastUpdate
+			total := s.totalUpdates
+			s.updateMetricsLock.Unlock()
+			if total == 0 {
+				log.Printf("[DEBUG] runner: no status updates yet...")
+			} else {
+				log.Printf("[DEBUG] runner: last status update %s ago (%d total)",
+					time.Now().Sub(last), total)
+			}
+
+		case <-s.doneCh:
+			return
+		}
+	}
+}
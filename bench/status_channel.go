@@ -0,0 +1,167 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sync"
+	"time"
+)
+
+// StatusFDEnv is the environment variable in which the runner passes the
+// status sub-command the number of a file descriptor open for writing, on
+// which status should be output instead of stdout. This keeps status apart
+// from anything else the implementation, or a library it uses, prints. It
+// is only set where the transport supports it, so implementations should
+// fall back to stdout if it is not. The channel must be claimed by writing
+// a line to it, which may be empty, as soon as the sub-command starts, or
+// status is read from stdout instead. Once claimed, as with stdout, status
+// is read until every process holding the descriptor open has closed it, so
+// it must not be inherited by processes which outlive the status
+// sub-command, such as daemons it starts.
+const StatusFDEnv = "SCHEDBENCH_STATUS_FD"
+
+// statusClaimWindow is how long the runner waits for the status channel to
+// be claimed before reading status from stdout instead. Implementations
+// which use the channel are expected to claim it as soon as they start.
+const statusClaimWindow = time.Second
+
+// statusMaxHeld is the number of lines read from stdout which are held
+// back while waiting for the status channel to be claimed, after which
+// status is read from stdout even if the claim window has not elapsed.
+const statusMaxHeld = 1000
+
+// scanStatus calls fn with each line of status output read from stdout and,
+// if not nil, the dedicated status channel, along with when it was read.
+// Calls to fn are serialized.
+//
+// An implementation opts in to the channel by writing a line to it, which
+// may be empty as empty lines on the channel are skipped. Once it has,
+// stdout is assumed to hold other output and is discarded. Until then, lines
+// read from stdout are held back, until the channel is closed, the claim
+// window elapses or statusMaxHeld lines are held, after which they are
+// passed to fn and stdout is read as status from then on.
+//
+// Returns once both are exhausted, except that an unclaimed channel is not
+// waited for once stdout is exhausted and the claim window has elapsed, in
+// case a process the implementation started holds it open.
+func scanStatus(stdout, channel io.Reader, fn func(line string, received time.Time)) {
+	if channel == nil {
+		scanLines(stdout, "stdout", nil, fn)
+		return
+	}
+
+	type heldLine struct {
+		line     string
+		received time.Time
+	}
+	var lock sync.Mutex
+	var claimed, unclaimed, abandoned, warned bool
+	var held []heldLine
+
+	// release stops holding back stdout, passing on the lines held so far
+	// unless the channel was claimed. The lock must be held.
+	release := func(reason string) {
+		if claimed || unclaimed {
+			return
+		}
+		log.Printf("[DEBUG] runner: reading status from stdout, as the status channel was %s", reason)
+		for _, h := range held {
+			fn(h.line, h.received)
+		}
+		unclaimed, held = true, nil
+	}
+
+	windowCh := make(chan struct{})
+	timer := time.AfterFunc(statusClaimWindow, func() {
+		lock.Lock()
+		defer lock.Unlock()
+		release("not claimed within " + statusClaimWindow.String())
+		close(windowCh)
+	})
+	defer timer.Stop()
+
+	channelDone := make(chan struct{})
+	go func() {
+		defer close(channelDone)
+		quiet := func() bool {
+			lock.Lock()
+			defer lock.Unlock()
+			return abandoned
+		}
+		scanLines(channel, "the status channel", quiet, func(line string, received time.Time) {
+			lock.Lock()
+			defer lock.Unlock()
+			switch {
+			case abandoned:
+				return
+			case unclaimed:
+				if !warned {
+					log.Printf("[WARN] runner: status channel used after status was read from stdout")
+					warned = true
+				}
+			case !claimed:
+				log.Printf("[DEBUG] runner: receiving status on the status channel; ignoring stdout")
+				if len(held) != 0 {
+					log.Printf("[WARN] runner: discarding %d status lines read from stdout before the status channel was used",
+						len(held))
+				}
+				claimed, held = true, nil
+			}
+			if line != "" {
+				fn(line, received)
+			}
+		})
+
+		lock.Lock()
+		release("closed without being claimed")
+		lock.Unlock()
+	}()
+
+	scanLines(stdout, "stdout", nil, func(line string, received time.Time) {
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case claimed:
+		case unclaimed:
+			fn(line, received)
+		default:
+			held = append(held, heldLine{line, received})
+			if len(held) >= statusMaxHeld {
+				release(fmt.Sprintf("not claimed within %d lines of stdout", statusMaxHeld))
+			}
+		}
+	})
+
+	select {
+	case <-channelDone:
+		return
+	case <-windowCh:
+	}
+	lock.Lock()
+	abandon := !claimed
+	abandoned = abandon
+	lock.Unlock()
+	if !abandon {
+		<-channelDone
+	}
+}
+
+// scanLines calls fn with each line read from r, along with when it was
+// read, until r is exhausted. If r can not be read, the error is logged
+// unless quiet returns true, and the remaining output is discarded so that
+// the implementation does not block writing it.
+func scanLines(r io.Reader, source string, quiet func() bool, fn func(line string, received time.Time)) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fn(scanner.Text(), time.Now())
+	}
+	if err := scanner.Err(); err != nil {
+		if quiet == nil || !quiet() {
+			log.Printf("[ERR] runner: failed reading payload from %s: %v", source, err)
+		}
+		io.Copy(ioutil.Discard, r)
+	}
+}
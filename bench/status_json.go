@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Status formats, which the runner advertises to the sub-commands of the
+// test implementation in the StatusFormatEnv environment variable.
+const (
+	// StatusFormatEnv is the environment variable naming the richest status
+	// format the runner accepts. Lines of status output in the text format
+	// are accepted regardless.
+	StatusFormatEnv = "SCHEDBENCH_STATUS_FORMAT"
+
+	// StatusFormatText is the "<metric>|<value>[|<timestamp>[|<kind>]]"
+	// line format, in which events are given as
+	// "!<name>|<message>[|<timestamp>]".
+	StatusFormatText = "text"
+
+	// StatusFormatJSON is the JSON Lines format, where each line of status
+	// output is a JSON object. Lines beginning with '{' are parsed as JSON.
+	StatusFormatJSON = "json"
+)
+
+// Event is a free-form message emitted by a test implementation as part of
+// its status, such as "all jobs submitted", which annotates the timeline of
+// the benchmark.
+type Event struct {
+	// Name optionally categorizes the event, and Message describes it.
+	Name    string `json:"name,omitempty"`
+	Message string `json:"message"`
+
+	// Labels holds the dimensions of the event. It is nil if it has none.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Time is when the event happened, as given by the implementation, or
+	// otherwise when the runner received it. Elapsed is the time in
+	// milliseconds measured from the origin of the benchmark, and is only
+	// set once the event has been collected.
+	Time    time.Time `json:"time"`
+	Elapsed int64     `json:"elapsed_ms"`
+}
+
+// eventPrefix begins a line of status output in the text format which is
+// an event rather than an update of a metric.
+const eventPrefix = "!"
+
+// ParseStatusEvent parses a single line of status output in the text
+// format which is an event, "!<name>|<message>[|<timestamp>]". The name may
+// be empty, and may be followed by labels as with metrics. If the timestamp
+// is not given, or is empty, the event is timestamped with now.
+func ParseStatusEvent(payload string, now time.Time) (*Event, error) {
+	if !strings.HasPrefix(payload, eventPrefix) {
+		return nil, fmt.Errorf("invalid event payload: %q", payload)
+	}
+	parts := strings.Split(payload[len(eventPrefix):], "|")
+	t := now
+	switch len(parts) {
+	case 2:
+		// Missing timestamp (will auto-generate)
+	case 3:
+		if parts[2] == "" {
+			break
+		}
+		ts, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing event timestamp in %q: %v", payload, err)
+		}
+		t = time.Unix(0, ts)
+	default:
+		return nil, fmt.Errorf("invalid event payload: %q", payload)
+	}
+
+	name, labels, err := ParseSeries(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing event labels in %q: %v", payload, err)
+	}
+	if strings.TrimSpace(parts[1]) == "" {
+		return nil, fmt.Errorf("event %q has no message", payload)
+	}
+	return &Event{Name: name, Message: parts[1], Labels: labels, Time: t}, nil
+}
+
+// statusRecord is a single line of status output in the JSON Lines format,
+// which is either an update of a metric or, if it has a message, an event.
+type statusRecord struct {
+	Name      string            `json:"name,omitempty"`
+	Value     *float64          `json:"value,omitempty"`
+	Timestamp *jsonTimestamp    `json:"timestamp,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Kind      string            `json:"kind,omitempty"`
+	Unit      string            `json:"unit,omitempty"`
+	Message   string            `json:"message,omitempty"`
+}
+
+// jsonTimestamp is a timestamp given either as a number of nanoseconds
+// since the Unix epoch, or as an RFC 3339 string.
+type jsonTimestamp time.Time
+
+func (t jsonTimestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UnixNano())
+}
+
+func (t *jsonTimestamp) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		v, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("timestamp must be in RFC 3339 format: %v", err)
+		}
+		*t = jsonTimestamp(v)
+		return nil
+	}
+	var ns int64
+	if err := json.Unmarshal(data, &ns); err != nil {
+		return fmt.Errorf("timestamp must be Unix time in nanoseconds or an RFC 3339 string")
+	}
+	*t = jsonTimestamp(time.Unix(0, ns))
+	return nil
+}
+
+// ParseStatusLine parses a single line of status output in either the text
+// or JSON Lines format, returning either the update of a metric or an event.
+// If the line has no timestamp, it is timestamped with now.
+func ParseStatusLine(line string, now time.Time) (*Update, *Event, error) {
+	switch {
+	case strings.HasPrefix(line, eventPrefix):
+		event, err := ParseStatusEvent(line, now)
+		return nil, event, err
+	case !strings.HasPrefix(line, "{"):
+		update, err := ParseStatusUpdate(line, now)
+		return update, nil, err
+	}
+
+	var rec statusRecord
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rec); err != nil {
+		return nil, nil, fmt.Errorf("invalid JSON status record %q: %v", line, err)
+	}
+	if strings.TrimSpace(line[dec.InputOffset():]) != "" {
+		return nil, nil, fmt.Errorf("invalid JSON status record %q: trailing data", line)
+	}
+	t := now
+	if rec.Timestamp != nil {
+		t = time.Time(*rec.Timestamp)
+	}
+
+	// Metric names may carry labels as in the text format, to which the
+	// labels of the record are added.
+	metric, labels, err := ParseSeries(rec.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing name in %q: %v", line, err)
+	}
+	for name, value := range rec.Labels {
+		if _, ok := labels[name]; ok {
+			return nil, nil, fmt.Errorf("label %q is given twice in %q", name, line)
+		}
+		if name == "" || strings.ContainsAny(name, labelReserved) || strings.ContainsAny(value, labelReserved) {
+			return nil, nil, fmt.Errorf("label %q contains reserved characters in %q", name, line)
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(rec.Labels))
+		}
+		labels[name] = value
+	}
+
+	if rec.Message != "" {
+		if rec.Value != nil || rec.Kind != "" || rec.Unit != "" {
+			return nil, nil, fmt.Errorf("event %q must not have a value, kind or unit", line)
+		}
+		return nil, &Event{Name: metric, Message: rec.Message, Labels: labels, Time: t}, nil
+	}
+
+	switch {
+	case metric == "":
+		return nil, nil, fmt.Errorf("JSON status record %q has no name or message", line)
+	case rec.Value == nil:
+		return nil, nil, fmt.Errorf("JSON status record %q has no value", line)
+	}
+	kind, err := ParseKind(rec.Kind)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed parsing metric kind in %q: %v", line, err)
+	}
+	return &Update{
+		Metric: metric,
+		Value:  *rec.Value,
+		Labels: labels,
+		Kind:   kind,
+		Unit:   rec.Unit,
+		Time:   t,
+	}, nil, nil
+}
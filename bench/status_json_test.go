@@ -0,0 +1,86 @@
+package bench
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStatusLine(t *testing.T) {
+	now := time.Unix(0, 1000)
+	ts := time.Date(2023, 7, 22, 4, 26, 40, 500000000, time.UTC)
+	cases := []struct {
+		line   string
+		update *Update
+		event  *Event
+	}{
+		// Lines in the text format are parsed as such
+		{"m|1", &Update{Metric: "m", Value: 1, Kind: KindGauge, Time: now}, nil},
+		{"!drain|node drained|123", nil, &Event{Name: "drain", Message: "node drained", Time: time.Unix(0, 123)}},
+
+		{
+			`{"name":"m","value":1}`,
+			&Update{Metric: "m", Value: 1, Kind: KindGauge, Time: now}, nil,
+		},
+		{
+			`{"name":"a|b","value":2.5,"timestamp":123,"kind":"sample","unit":"ms"}`,
+			&Update{Metric: "a|b", Value: 2.5, Kind: KindSample, Unit: "ms", Time: time.Unix(0, 123)}, nil,
+		},
+		{
+			`{"name":"m","value":1,"timestamp":"2023-07-22T04:26:40.5Z"}`,
+			&Update{Metric: "m", Value: 1, Kind: KindGauge, Time: ts}, nil,
+		},
+		{
+			`{"name":"m","value":0,"timestamp":1690000000500000000}`,
+			&Update{Metric: "m", Value: 0, Kind: KindGauge, Time: time.Unix(0, 1690000000500000000)}, nil,
+		},
+		{
+			// Labels of the record are merged with those of the name
+			`{"name":"running{class=a}","value":3,"labels":{"node":"x"}}`,
+			&Update{Metric: "running", Value: 3, Labels: map[string]string{"class": "a", "node": "x"}, Kind: KindGauge, Time: now}, nil,
+		},
+		{
+			`{"name":"running","value":3,"labels":{"node":"x"}}`,
+			&Update{Metric: "running", Value: 3, Labels: map[string]string{"node": "x"}, Kind: KindGauge, Time: now}, nil,
+		},
+		{
+			`{"message":"all jobs submitted","timestamp":123}`,
+			nil, &Event{Message: "all jobs submitted", Time: time.Unix(0, 123)},
+		},
+		{
+			`{"name":"drain{node=x}","message":"node drained","labels":{"class":"a"}}`,
+			nil, &Event{Name: "drain", Message: "node drained", Labels: map[string]string{"class": "a", "node": "x"}, Time: now},
+		},
+
+		// Invalid records
+		{`{"name":"m","value":1,"extra":true}`, nil, nil},
+		{`{"name":"m","value":1} trailing`, nil, nil},
+		{`{"name":"m","value":1}{"name":"n","value":2}`, nil, nil},
+		{`{"name":"m","value":"1"}`, nil, nil},
+		{`{"name":"m"}`, nil, nil},
+		{`{"value":1}`, nil, nil},
+		{`{"name":"m","value":1,"kind":"histogram"}`, nil, nil},
+		{`{"name":"m","value":1,"timestamp":"yesterday"}`, nil, nil},
+		{`{"name":"m","value":1,"timestamp":1.5}`, nil, nil},
+		{`{"name":"m{class=a}","value":1,"labels":{"class":"b"}}`, nil, nil},
+		{`{"name":"m","value":1,"labels":{"class":"a,b"}}`, nil, nil},
+		{`{"name":"m","value":1,"labels":{"":"a"}}`, nil, nil},
+		{`{"name":"m{class","value":1}`, nil, nil},
+		{`{"message":"done","value":1}`, nil, nil},
+		{`{"message":"done","unit":"ms"}`, nil, nil},
+		{`{"name":"m","value":1`, nil, nil},
+	}
+	for _, c := range cases {
+		update, event, err := ParseStatusLine(c.line, now)
+		switch {
+		case c.update == nil && c.event == nil && err == nil:
+			t.Fatalf("expected %q to be invalid, got %+v %+v", c.line, update, event)
+		case (c.update != nil || c.event != nil) && err != nil:
+			t.Fatalf("failed parsing %q: %v", c.line, err)
+		case !reflect.DeepEqual(update, c.update):
+			t.Fatalf("unexpected update parsed from %q: %+v", c.line, update)
+		case !reflect.DeepEqual(event, c.event):
+			t.Fatalf("unexpected event parsed from %q: %+v", c.line, event)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseStatusUpdate(t *testing.T) {
+	now := time.Unix(0, 1000)
+	cases := []struct {
+		payload string
+		update  *Update
+	}{
+		{"m|1", &Update{Metric: "m", Value: 1, Kind: KindGauge, Time: now}},
+		{"m|1|", &Update{Metric: "m", Value: 1, Kind: KindGauge, Time: now}},
+		{"m|1|123", &Update{Metric: "m", Value: 1, Kind: KindGauge, Time: time.Unix(0, 123)}},
+		{"m|1||counter", &Update{Metric: "m", Value: 1, Kind: KindCounter, Time: now}},
+		{"m|1|123|delta", &Update{Metric: "m", Value: 1, Kind: KindDelta, Time: time.Unix(0, 123)}},
+		{"m|1|123|", &Update{Metric: "m", Value: 1, Kind: KindGauge, Time: time.Unix(0, 123)}},
+		{"m|2.5||sample", &Update{Metric: "m", Value: 2.5, Kind: KindSample, Time: now}},
+		{"m{a=1,b=2}|1", &Update{Metric: "m", Value: 1, Labels: map[string]string{"a": "1", "b": "2"}, Kind: KindGauge, Time: now}},
+
+		// Invalid payloads
+		{"m", nil},
+		{"m|", nil},
+		{"m|one", nil},
+		{"m|1|soon", nil},
+		{"m|1|123|histogram", nil},
+		{"m|1|123|delta|extra", nil},
+		{"m{a}|1", nil},
+	}
+	for _, c := range cases {
+		update, err := ParseStatusUpdate(c.payload, now)
+		switch {
+		case c.update == nil && err == nil:
+			t.Fatalf("expected %q to be invalid, got %+v", c.payload, update)
+		case c.update != nil && err != nil:
+			t.Fatalf("failed parsing %q: %v", c.payload, err)
+		case !reflect.DeepEqual(update, c.update):
+			t.Fatalf("unexpected update parsed from %q: %+v", c.payload, update)
+		}
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	for s, want := range map[string]Kind{
+		"":        KindGauge,
+		"gauge":   KindGauge,
+		"counter": KindCounter,
+		"delta":   KindDelta,
+		"sample":  KindSample,
+	} {
+		kind, err := ParseKind(s)
+		if err != nil || kind != want {
+			t.Fatalf("expected %q to be a %s, got %q: %v", s, want, kind, err)
+		}
+	}
+	if _, err := ParseKind("Gauge"); err == nil {
+		t.Fatalf("expected kinds to be case-sensitive")
+	}
+}
+
+// collect returns the result of the status server collecting the lines of
+// status output, each given as the elapsed time in milliseconds of its
+// timestamp and the rest of the line, such as "started|1|%d|delta".
+func collect(t *testing.T, origin time.Time, lines ...string) *Result {
+	t.Helper()
+	var out strings.Builder
+	for _, line := range lines {
+		var ms int64
+		var format string
+		if _, err := fmt.Sscanf(line, "%d %s", &ms, &format); err != nil {
+			t.Fatalf("invalid line %q: %v", line, err)
+		}
+		fmt.Fprintf(&out, format+"\n", origin.Add(time.Duration(ms)*time.Millisecond).UnixNano())
+	}
+
+	srv := newStatusServer(strings.NewReader(out.String()), nil, nil)
+	go srv.run()
+	res := &Result{}
+	srv.wait(origin, res)
+	return res
+}
+
+func TestStatusServer_Kinds(t *testing.T) {
+	// The updates are output out of order, and aggregated in time order
+	origin := time.Unix(1000, 0)
+	res := collect(t, origin,
+		"3 placed|1|%d|counter",
+		"1 placed|2|%d|counter",
+		"2 placed|4|%d|counter",
+		"4 placed|3|%d|counter",
+		"2 started|1|%d|delta",
+		"1 started|1|%d|delta",
+		"3 started|2|%d|delta",
+		"2 latency|5|%d|sample",
+		"1 latency|7|%d|sample",
+		"1 queued|3|%d",
+		"2 queued|1|%d",
+	)
+
+	expected := Metrics{
+		0: {"running": 0},
+		1: {"placed": 2, "started": 1, "queued": 3},
+		2: {"placed": 4, "started": 2, "queued": 1},
+		3: {"placed": 5, "started": 4},
+		4: {"placed": 7},
+	}
+	if !reflect.DeepEqual(res.Metrics, expected) {
+		t.Fatalf("unexpected metrics %v", res.Metrics)
+	}
+	samples := Samples{"latency": {{Elapsed: 1, Value: 7}, {Elapsed: 2, Value: 5}}}
+	if !reflect.DeepEqual(res.Samples, samples) {
+		t.Fatalf("unexpected samples %v", res.Samples)
+	}
+}
+
+func TestStatusServer_KindConflict(t *testing.T) {
+	// Updates of a series as another kind than it was first reported as
+	// are ignored
+	res := collect(t, time.Unix(1000, 0),
+		"1 placed|2|%d|counter",
+		"2 placed|1|%d",
+		"3 placed|1|%d|delta",
+		"4 placed|3|%d|counter",
+	)
+	expected := Metrics{
+		0: {"running": 0},
+		1: {"placed": 2},
+		4: {"placed": 3},
+	}
+	if !reflect.DeepEqual(res.Metrics, expected) {
+		t.Fatalf("unexpected metrics %v", res.Metrics)
+	}
+}
+
+func TestStatusServer_Running(t *testing.T) {
+	// The clock is started with 0 running in each series which was not
+	// reported by the origin
+	res := collect(t, time.Unix(1000, 0),
+		"-5 running{class=a}|1|%d",
+		"10 running{class=b}|2|%d",
+	)
+	expected := Metrics{
+		-5: {"running{class=a}": 1},
+		0:  {"running{class=b}": 0},
+		10: {"running{class=b}": 2},
+	}
+	if !reflect.DeepEqual(res.Metrics, expected) {
+		t.Fatalf("unexpected metrics %v", res.Metrics)
+	}
+}
+
+func TestStatusServer_UnclaimedChannel(t *testing.T) {
+	// Status output on stdout is collected as it is output if the status
+	// channel is not claimed, while the channel is still open
+	var logs syncBuffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	defer func(d time.Duration) { statusLogInterval = d }(statusLogInterval)
+	statusLogInterval = 10 * time.Millisecond
+
+	stdoutR, stdoutW := io.Pipe()
+	channelR, channelW := io.Pipe()
+	updateCh := make(chan *Update, 1)
+	srv := newStatusServer(stdoutR, channelR, func(update *Update) {
+		updateCh <- update
+	})
+	go srv.run()
+
+	fmt.Fprintln(stdoutW, "running|1")
+	select {
+	case update := <-updateCh:
+		if update.Metric != "running" || update.Value != 1 {
+			t.Fatalf("unexpected update %+v", update)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the update before stdout was closed")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logs.String(), "last status update") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the update to be logged:\n%s", logs.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The channel is not waited for once stdout is closed
+	stdoutW.Close()
+	done := make(chan struct{})
+	go func() {
+		srv.wait(time.Now(), &Result{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected status to complete with the channel open")
+	}
+	channelW.Close()
+}
+
+// syncBuffer is a bytes.Buffer which may be written and read concurrently.
+type syncBuffer struct {
+	lock sync.Mutex
+	buf  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.buf.String()
+}
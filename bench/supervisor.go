@@ -0,0 +1,108 @@
+package bench
+
+import (
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrInterrupted is returned when a step is not started because the
+// benchmark was interrupted.
+var ErrInterrupted = errors.New("interrupted")
+
+// Supervisor tracks the steps which are currently executing and forwards
+// interruptions to them. A single Supervisor may be shared by several
+// Runners, so that an interruption stops all remaining work.
+type Supervisor struct {
+	// active holds the sub-commands which are currently executing, and
+	// interrupted is the first signal the benchmark was interrupted by, if
+	// any.
+	active      map[*phaseCmd]struct{}
+	interrupted os.Signal
+	lock        sync.Mutex
+
+	// interruptCh is closed when the first interruption is received.
+	interruptCh chan struct{}
+}
+
+// NewSupervisor creates a Supervisor.
+func NewSupervisor() *Supervisor {
+	return &Supervisor{
+		active:      make(map[*phaseCmd]struct{}),
+		interruptCh: make(chan struct{}),
+	}
+}
+
+// Interrupt stops the benchmark. On the first interruption, the signal is
+// forwarded to the active steps, and no further steps other than teardown
+// are started. Any further interruptions kill the active steps outright.
+func (s *Supervisor) Interrupt(sig syscall.Signal) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	fwd := sig
+	if s.interrupted == nil {
+		s.interrupted = sig
+		close(s.interruptCh)
+	} else {
+		fwd = syscall.SIGKILL
+	}
+	for cmd := range s.active {
+		if err := cmd.signal(fwd); err != nil {
+			log.Printf("[ERR] runner: failed forwarding %v to step '%s': %v",
+				fwd, cmd.phase, err)
+		}
+	}
+}
+
+// Interrupted returns the first signal the benchmark was interrupted by, or
+// nil if it has not been interrupted.
+func (s *Supervisor) Interrupted() os.Signal {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.interrupted
+}
+
+// Sleep pauses for the given duration, such as a cooldown between
+// benchmarks. Returns ErrInterrupted early if the benchmark is interrupted.
+func (s *Supervisor) Sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	log.Printf("[DEBUG] runner: cooling down for %s", d)
+	select {
+	case <-time.After(d):
+		return nil
+	case <-s.interruptCh:
+		return ErrInterrupted
+	}
+}
+
+// start starts the sub-command and tracks it as active. Steps other than
+// teardown are not started once the benchmark has been interrupted.
+func (s *Supervisor) start(cmd *phaseCmd) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.interrupted != nil && cmd.phase != "teardown" {
+		return ErrInterrupted
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	s.active[cmd] = struct{}{}
+	return nil
+}
+
+// wait waits for an active sub-command to exit and stops tracking it.
+func (s *Supervisor) wait(cmd *phaseCmd) error {
+	err := cmd.Wait()
+
+	s.lock.Lock()
+	delete(s.active, cmd)
+	s.lock.Unlock()
+	return err
+}
@@ -0,0 +1,72 @@
+package bench
+
+import (
+	"sort"
+	"time"
+)
+
+// Origins from which the elapsed time of the benchmark can be measured.
+const (
+	// OriginRun measures time from the start of the run step, so that the
+	// results reflect only the work the scheduler was asked to do.
+	OriginRun = "run"
+
+	// OriginStatus measures time from the start of the status step, which
+	// is started shortly before the run step.
+	OriginStatus = "status"
+)
+
+// TimelineEvent marks the start or end of a step of the benchmark.
+type TimelineEvent struct {
+	// Elapsed is the time of the event in milliseconds, measured from the
+	// origin of the benchmark.
+	Elapsed int64
+	Time    time.Time
+
+	// Phase is the step, and Event is either "start" or "end".
+	Phase string
+	Event string
+}
+
+// timeline returns the start and end of every step which was started, in
+// time order, with elapsed times in milliseconds measured from the origin.
+func timeline(steps []*Step, origin time.Time) []TimelineEvent {
+	elapsed := func(t time.Time) int64 {
+		return int64(t.Sub(origin) / time.Millisecond)
+	}
+
+	var events []TimelineEvent
+	for _, step := range steps {
+		events = append(events, TimelineEvent{
+			Elapsed: elapsed(step.Start),
+			Time:    step.Start,
+			Phase:   step.Phase,
+			Event:   "start",
+		})
+		if !step.End.IsZero() {
+			events = append(events, TimelineEvent{
+				Elapsed: elapsed(step.End),
+				Time:    step.End,
+				Phase:   step.Phase,
+				Event:   "end",
+			})
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Elapsed < events[j].Elapsed
+	})
+	return events
+}
+
+// phaseDurations returns the time taken by every step which ran to
+// completion, in milliseconds, as metrics such as "run_duration_ms".
+func phaseDurations(steps []*Step) map[string]float64 {
+	durations := make(map[string]float64, len(steps))
+	for _, step := range steps {
+		if step.End.IsZero() {
+			continue
+		}
+		durations[step.Phase+"_duration_ms"] = float64(step.Duration()) / float64(time.Millisecond)
+	}
+	return durations
+}
@@ -0,0 +1,118 @@
+package bench
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// process is a single invocation of a sub-command of a test implementation,
+// as carried out by the transport the implementation is reached by.
+type process interface {
+	// stdoutPipe returns a pipe connected to stdout once the process
+	// starts, and is called instead of passing a stdout writer to start.
+	stdoutPipe() (io.ReadCloser, error)
+
+	// statusPipe returns a pipe connected to a dedicated status channel
+	// once the process starts, or nil if the transport has none. It must be
+	// called before start.
+	statusPipe() (io.ReadCloser, error)
+
+	// start starts the process, writing its output to stdout and stderr.
+	// Either may be nil to discard the output.
+	start(stdout, stderr io.Writer) error
+
+	// wait waits for the process to exit, returning an error if it failed.
+	wait() error
+
+	// signal asks the process to stop. SIGKILL stops it forcefully.
+	signal(sig syscall.Signal) error
+
+	// exitCode returns the exit code of the process once it has exited.
+	exitCode() int
+}
+
+// IsRemote returns whether the path of a test implementation is the URL of
+// an implementation served over HTTP rather than an executable.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// newProcess creates a process invoking the given sub-command of the test
+// implementation at path, using the transport matching the path. If the
+// implementation is a plugin which is not yet running, it is launched with
+// its stderr written to stderr.
+func newProcess(path, phase string, env []string, stderr io.Writer) process {
+	switch {
+	case IsRemote(path):
+		return newHTTPProcess(path, phase, env)
+	case IsPlugin(path):
+		return newPluginProcess(path, phase, env, stderr)
+	}
+	return newExecProcess(path, phase, env)
+}
+
+// execProcess invokes a sub-command by forking the executable of the test
+// implementation with the sub-command as its argument. Each sub-command is
+// started in its own process group so that it can be terminated along with
+// any children it spawned.
+type execProcess struct {
+	cmd *exec.Cmd
+
+	// statusW is the write end of the status channel, if any, which is
+	// closed once the process has inherited it.
+	statusW *os.File
+}
+
+// newExecProcess creates an execProcess. The sub-command inherits the
+// environment of the runner, with any variables in env added or overridden.
+func newExecProcess(path, phase string, env []string) *execProcess {
+	cmd := exec.Command(path, phase)
+	if len(env) != 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	setProcessGroup(cmd)
+	return &execProcess{cmd: cmd}
+}
+
+func (p *execProcess) stdoutPipe() (io.ReadCloser, error) {
+	return p.cmd.StdoutPipe()
+}
+
+func (p *execProcess) statusPipe() (io.ReadCloser, error) {
+	r, w, err := statusChannel(p.cmd)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	p.statusW = w
+	return r, nil
+}
+
+func (p *execProcess) start(stdout, stderr io.Writer) error {
+	if stdout != nil {
+		p.cmd.Stdout = stdout
+	}
+	p.cmd.Stderr = stderr
+	err := p.cmd.Start()
+	if p.statusW != nil {
+		p.statusW.Close()
+	}
+	return err
+}
+
+func (p *execProcess) wait() error {
+	return p.cmd.Wait()
+}
+
+func (p *execProcess) signal(sig syscall.Signal) error {
+	return signalProcessGroup(p.cmd.Process, sig)
+}
+
+func (p *execProcess) exitCode() int {
+	if p.cmd.ProcessState == nil {
+		return -1
+	}
+	return p.cmd.ProcessState.ExitCode()
+}
@@ -0,0 +1,155 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+// httpClient is used to drive implementations served over HTTP. It has no
+// timeout, since steps such as status stream their output for as long as
+// the benchmark runs; timeouts are enforced per step instead.
+var httpClient = &http.Client{}
+
+// httpRequest is the body of the request made to invoke a sub-command of an
+// implementation served over HTTP.
+type httpRequest struct {
+	// Env holds the additional environment variables of the benchmark. An
+	// implementation served over HTTP does not inherit the environment of
+	// the runner.
+	Env map[string]string `json:"env"`
+}
+
+// httpProcess invokes a sub-command of an implementation served over HTTP
+// by POSTing to the path named after it, such as /setup. The sub-command
+// succeeds if it responds with a 2xx status, and the body of the response
+// is its stdout, which may be streamed as with the status sub-command. The
+// body of any other response is written to its stderr instead, so that an
+// error page is not mistaken for output. Stopping the sub-command cancels
+// the request.
+type httpProcess struct {
+	url  string
+	body []byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// pipe is the writer of the pipe returned by stdoutPipe, if any.
+	pipe *io.PipeWriter
+
+	// doneCh is closed once the request completes, setting err and code.
+	doneCh chan struct{}
+	err    error
+	code   int
+}
+
+// newHTTPProcess creates an httpProcess for the implementation at the base
+// URL. The variables in env are sent with the request.
+func newHTTPProcess(base, phase string, env []string) *httpProcess {
+	req := &httpRequest{Env: make(map[string]string, len(env))}
+	for _, kv := range env {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			req.Env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+	body, _ := json.Marshal(req)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &httpProcess{
+		url:    strings.TrimSuffix(base, "/") + "/" + phase,
+		body:   body,
+		ctx:    ctx,
+		cancel: cancel,
+		doneCh: make(chan struct{}),
+		code:   -1,
+	}
+}
+
+func (p *httpProcess) stdoutPipe() (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	p.pipe = w
+	return r, nil
+}
+
+// statusPipe returns no pipe, since the body of the response is only the
+// output of the sub-command.
+func (p *httpProcess) statusPipe() (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (p *httpProcess) start(stdout, stderr io.Writer) error {
+	req, err := http.NewRequest("POST", p.url, bytes.NewReader(p.body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(p.ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+	if p.pipe != nil {
+		stdout = p.pipe
+	}
+	go p.do(req, stdout, stderr)
+	return nil
+}
+
+// do makes the request, copying the body of the response to stdout if it
+// succeeded and to stderr otherwise.
+func (p *httpProcess) do(req *http.Request, stdout, stderr io.Writer) {
+	defer close(p.doneCh)
+	defer p.cancel()
+	if p.pipe != nil {
+		defer p.pipe.Close()
+	}
+
+	resp, err := httpClient.Do(req)
+	if err == nil {
+		out := stdout
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			out = stderr
+		}
+		_, err = io.Copy(out, resp.Body)
+		resp.Body.Close()
+	}
+	switch {
+	case p.ctx.Err() != nil:
+		p.err = fmt.Errorf("request to %s was canceled", p.url)
+	case err != nil:
+		p.err = err
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		p.err = fmt.Errorf("unexpected response from %s: %s", p.url, resp.Status)
+		p.code = 1
+	default:
+		p.code = 0
+	}
+}
+
+func (p *httpProcess) wait() error {
+	<-p.doneCh
+	return p.err
+}
+
+func (p *httpProcess) signal(sig syscall.Signal) error {
+	p.cancel()
+	return nil
+}
+
+func (p *httpProcess) exitCode() int {
+	select {
+	case <-p.doneCh:
+		return p.code
+	default:
+		return -1
+	}
+}
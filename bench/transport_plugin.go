@@ -0,0 +1,293 @@
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/schedbench/plugin"
+	"github.com/hashicorp/schedbench/sdk"
+)
+
+// PluginPrefix prefixes the path of a test implementation served as a Go
+// plugin rather than implementing the sub-commands.
+const PluginPrefix = "plugin:"
+
+// IsPlugin returns whether the path of a test implementation refers to a Go
+// plugin.
+func IsPlugin(path string) bool {
+	return strings.HasPrefix(path, PluginPrefix)
+}
+
+// plugins holds the plugin clients which have been launched, keyed by the
+// executable and environment of the plugin. A plugin is launched when the
+// first of its steps starts, and is reused by the following steps so that
+// it may keep state between them.
+var plugins = struct {
+	clients map[string]*plugin.Client
+	lock    sync.Mutex
+}{clients: make(map[string]*plugin.Client)}
+
+// pluginClient returns the client of the plugin at path with the given
+// environment, launching the plugin with its stderr written to stderr if it
+// is not running.
+func pluginClient(path string, env []string, stderr io.Writer) (*plugin.Client, error) {
+	key := path + "\x00" + strings.Join(env, "\x00")
+
+	plugins.lock.Lock()
+	defer plugins.lock.Unlock()
+	if c, ok := plugins.clients[key]; ok && !c.Exited() {
+		return c, nil
+	}
+
+	cmd := exec.Command(path)
+	cmd.Env = append(os.Environ(), env...)
+	c, err := plugin.NewClient(cmd, stderr)
+	if err != nil {
+		return nil, err
+	}
+	plugins.clients[key] = c
+	return c, nil
+}
+
+// ClosePlugins stops any plugins which have been launched. Plugins are not
+// stopped when a Runner completes, since they are shared between Runners,
+// so it must be called once no more benchmarks will be run, such as with a
+// defer in main. Plugins launched after it is called are stopped by the
+// next call.
+func ClosePlugins() {
+	plugins.lock.Lock()
+	defer plugins.lock.Unlock()
+	for key, c := range plugins.clients {
+		c.Kill()
+		delete(plugins.clients, key)
+	}
+}
+
+// pluginProcess invokes a step of a test implementation served as a Go
+// plugin by making the RPC named after the sub-command. The output of the
+// RPC is written to stdout in the format of the matching sub-command, so
+// that the rest of the runner handles plugins as any other implementation.
+// Stopping the step cancels the RPC, and killing it with SIGKILL kills the
+// plugin, in case it does not return from a canceled RPC. A killed plugin
+// is launched again by the next step, such as teardown.
+type pluginProcess struct {
+	path   string
+	phase  string
+	env    []string
+	stderr io.Writer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// client is the client of the plugin the RPC is made to, once started.
+	client     *plugin.Client
+	clientLock sync.Mutex
+
+	// pipe is the writer of the pipe returned by stdoutPipe, if any.
+	pipe *io.PipeWriter
+
+	// doneCh is closed once the RPC completes, setting err and code.
+	doneCh chan struct{}
+	err    error
+	code   int
+}
+
+// newPluginProcess creates a pluginProcess for the plugin at path, which
+// includes PluginPrefix. The plugin inherits the environment of the runner,
+// with any variables in env added or overridden.
+func newPluginProcess(path, phase string, env []string, stderr io.Writer) *pluginProcess {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &pluginProcess{
+		path:   strings.TrimPrefix(path, PluginPrefix),
+		phase:  phase,
+		env:    env,
+		stderr: stderr,
+		ctx:    ctx,
+		cancel: cancel,
+		doneCh: make(chan struct{}),
+		code:   -1,
+	}
+}
+
+func (p *pluginProcess) stdoutPipe() (io.ReadCloser, error) {
+	r, w := io.Pipe()
+	p.pipe = w
+	return r, nil
+}
+
+// statusPipe returns no pipe, since status updates are received over the
+// RPC rather than the output of the plugin.
+func (p *pluginProcess) statusPipe() (io.ReadCloser, error) {
+	return nil, nil
+}
+
+func (p *pluginProcess) start(stdout, stderr io.Writer) error {
+	client, err := pluginClient(p.path, p.env, p.stderr)
+	if err != nil {
+		return err
+	}
+	p.clientLock.Lock()
+	p.client = client
+	p.clientLock.Unlock()
+
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if p.pipe != nil {
+		stdout = p.pipe
+	}
+	go p.do(client, stdout)
+	return nil
+}
+
+// do makes the RPC of the step, writing its output to stdout.
+func (p *pluginProcess) do(client *plugin.Client, stdout io.Writer) {
+	defer close(p.doneCh)
+	defer p.cancel()
+	if p.pipe != nil {
+		defer p.pipe.Close()
+	}
+
+	var err error
+	switch p.phase {
+	case "info":
+		err = pluginInfo(p.ctx, client, stdout)
+	case "setup":
+		err = client.Setup(p.ctx)
+	case "run":
+		err = client.Run(p.ctx)
+	case "status":
+		err = client.Status(p.ctx, &statusWriter{w: stdout})
+	case "validate":
+		err = pluginValidate(p.ctx, client, stdout)
+	case "teardown":
+		err = client.Teardown(p.ctx)
+	default:
+		err = fmt.Errorf("unknown sub-command %q", p.phase)
+	}
+
+	switch {
+	case p.ctx.Err() != nil:
+		p.err = fmt.Errorf("%s of plugin %s was canceled", p.phase, p.path)
+	case err != nil:
+		p.err = fmt.Errorf("%s of plugin %s failed: %v", p.phase, p.path, err)
+		p.code = 1
+	default:
+		p.code = 0
+	}
+}
+
+func (p *pluginProcess) wait() error {
+	<-p.doneCh
+	return p.err
+}
+
+func (p *pluginProcess) signal(sig syscall.Signal) error {
+	p.cancel()
+	if sig != syscall.SIGKILL {
+		return nil
+	}
+	p.clientLock.Lock()
+	client := p.client
+	p.clientLock.Unlock()
+	if client != nil {
+		// Killing the plugin may wait for it to exit gracefully first
+		go client.Kill()
+	}
+	return nil
+}
+
+func (p *pluginProcess) exitCode() int {
+	select {
+	case <-p.doneCh:
+		return p.code
+	default:
+		return -1
+	}
+}
+
+// pluginInfo writes the info of the plugin to w as JSON, as output by the
+// info sub-command.
+func pluginInfo(ctx context.Context, client *plugin.Client, w io.Writer) error {
+	info, err := client.Info(ctx)
+	if err != nil {
+		return err
+	}
+	return json.NewEncoder(w).Encode(&Info{
+		Name:             info.Name,
+		Version:          info.Version,
+		SchedulerVersion: info.SchedulerVersion,
+		RequiredEnv:      info.RequiredEnv,
+		Metrics:          info.Metrics,
+		ExpectedTasks:    info.ExpectedTasks,
+		Validate:         info.Validate,
+	})
+}
+
+// pluginValidate writes the output of validating the benchmark to w, and
+// returns an error if the plugin found it invalid.
+func pluginValidate(ctx context.Context, client *plugin.Client, w io.Writer) error {
+	out, valid, err := client.Validate(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, out); err != nil {
+		return err
+	}
+	if !valid {
+		return errors.New("benchmark is invalid")
+	}
+	return nil
+}
+
+// statusWriter writes status updates received from a plugin as the lines
+// output by the status sub-command, in the JSON Lines format so that metric
+// names are not restricted.
+type statusWriter struct {
+	w io.Writer
+}
+
+func (s *statusWriter) Update(metric string, value float64, t time.Time) error {
+	return s.UpdateKind(metric, sdk.KindGauge, value, t)
+}
+
+func (s *statusWriter) UpdateKind(metric string, kind sdk.Kind, value float64, t time.Time) error {
+	return s.UpdateMetric(&sdk.Metric{Name: metric, Kind: kind}, value, t)
+}
+
+func (s *statusWriter) UpdateMetric(m *sdk.Metric, value float64, t time.Time) error {
+	rec := &statusRecord{Name: m.Name, Value: &value, Labels: m.Labels, Unit: m.Unit}
+	if m.Kind != "" && m.Kind != sdk.KindGauge {
+		rec.Kind = string(m.Kind)
+	}
+	return s.write(rec, t)
+}
+
+func (s *statusWriter) Event(name, message string, t time.Time) error {
+	return s.write(&statusRecord{Name: name, Message: message}, t)
+}
+
+// write writes the record as a line of JSON, timestamped with t unless it is
+// zero.
+func (s *statusWriter) write(rec *statusRecord, t time.Time) error {
+	if !t.IsZero() {
+		ts := jsonTimestamp(t)
+		rec.Timestamp = &ts
+	}
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(append(out, '\n'))
+	return err
+}
@@ -0,0 +1,176 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/schedbench/plugin/proto"
+	"github.com/hashicorp/schedbench/sdk"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnsupported is returned by Client when the plugin does not implement an
+// optional method.
+var ErrUnsupported = errors.New("not supported by the plugin")
+
+// Client launches a plugin and drives the benchmark it serves.
+type Client struct {
+	client *goplugin.Client
+	impl   proto.BenchmarkClient
+}
+
+// NewClient launches the plugin executed by cmd and connects to it. Both
+// the stdout and stderr of the plugin are written to stderr, since stdout is
+// used to establish the connection.
+func NewClient(cmd *exec.Cmd, stderr io.Writer) (*Client, error) {
+	client := goplugin.NewClient(&goplugin.ClientConfig{
+		HandshakeConfig: Handshake,
+		Plugins: goplugin.PluginSet{
+			pluginName: &GRPCPlugin{},
+		},
+		Cmd:              cmd,
+		AllowedProtocols: []goplugin.Protocol{goplugin.ProtocolGRPC},
+		Stderr:           stderr,
+		SyncStdout:       stderr,
+		SyncStderr:       stderr,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:  "plugin",
+			Level: hclog.Off,
+		}),
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed launching plugin: %v", err)
+	}
+	raw, err := rpcClient.Dispense(pluginName)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed connecting to plugin: %v", err)
+	}
+	return &Client{
+		client: client,
+		impl:   raw.(proto.BenchmarkClient),
+	}, nil
+}
+
+// Exited returns whether the plugin process has exited.
+func (c *Client) Exited() bool {
+	return c.client.Exited()
+}
+
+// Kill stops the plugin process, forcefully if it does not exit gracefully.
+func (c *Client) Kill() {
+	c.client.Kill()
+}
+
+// Info describes the implementation. ErrUnsupported is returned if the
+// plugin does not implement Describer.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	resp, err := c.impl.Info(ctx, &proto.Empty{})
+	if err != nil {
+		return nil, convertErr(err)
+	}
+	return &Info{
+		Name:             resp.Name,
+		Version:          resp.Version,
+		SchedulerVersion: resp.SchedulerVersion,
+		RequiredEnv:      resp.RequiredEnv,
+		Metrics:          resp.Metrics,
+		ExpectedTasks:    int(resp.ExpectedTasks),
+		Validate:         resp.Validate,
+	}, nil
+}
+
+func (c *Client) Setup(ctx context.Context) error {
+	_, err := c.impl.Setup(ctx, &proto.Empty{})
+	return convertErr(err)
+}
+
+func (c *Client) Run(ctx context.Context) error {
+	_, err := c.impl.Run(ctx, &proto.Empty{})
+	return convertErr(err)
+}
+
+// Status streams the status updates of the benchmark to w until all work
+// has completed.
+func (c *Client) Status(ctx context.Context, w StatusWriter) error {
+	stream, err := c.impl.Status(ctx, &proto.Empty{})
+	if err != nil {
+		return convertErr(err)
+	}
+	for {
+		update, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return convertErr(err)
+		}
+		var t time.Time
+		if update.Timestamp != 0 {
+			t = time.Unix(0, update.Timestamp)
+		}
+		if update.Message != "" {
+			if err := w.Event(update.Metric, update.Message, t); err != nil {
+				return err
+			}
+			continue
+		}
+		m := &sdk.Metric{
+			Name:   update.Metric,
+			Kind:   sdk.Kind(update.Kind),
+			Labels: update.Labels,
+			Unit:   update.Unit,
+		}
+		if m.Kind == "" {
+			m.Kind = sdk.KindGauge
+		}
+		if err := w.UpdateMetric(m, update.Value, t); err != nil {
+			return err
+		}
+	}
+}
+
+// Validate checks the correctness of the benchmark, returning the output
+// of the check and whether the benchmark is valid. ErrUnsupported is
+// returned if the plugin does not implement Validator.
+func (c *Client) Validate(ctx context.Context) (string, bool, error) {
+	resp, err := c.impl.Validate(ctx, &proto.Empty{})
+	if err != nil {
+		return "", false, convertErr(err)
+	}
+	return resp.Output, resp.Valid, nil
+}
+
+func (c *Client) Teardown(ctx context.Context) error {
+	_, err := c.impl.Teardown(ctx, &proto.Empty{})
+	return convertErr(err)
+}
+
+// convertErr converts a gRPC error into a plain error, with the message the
+// implementation returned.
+func convertErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.Unimplemented:
+		return ErrUnsupported
+	case codes.Canceled:
+		return context.Canceled
+	}
+	return errors.New(s.Message())
+}
@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/schedbench/plugin/proto"
+	"github.com/hashicorp/schedbench/sdk"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCPlugin implements the go-plugin interfaces for serving and consuming a
+// Benchmark over gRPC. Only Impl needs to be set when serving.
+type GRPCPlugin struct {
+	goplugin.NetRPCUnsupportedPlugin
+	Impl Benchmark
+}
+
+func (p *GRPCPlugin) GRPCServer(broker *goplugin.GRPCBroker, s *grpc.Server) error {
+	proto.RegisterBenchmarkServer(s, &grpcServer{impl: p.Impl})
+	return nil
+}
+
+func (p *GRPCPlugin) GRPCClient(ctx context.Context, broker *goplugin.GRPCBroker, c *grpc.ClientConn) (interface{}, error) {
+	return proto.NewBenchmarkClient(c), nil
+}
+
+// grpcServer serves a Benchmark implementation over gRPC.
+type grpcServer struct {
+	proto.UnimplementedBenchmarkServer
+	impl Benchmark
+
+	// configureOnce guards configuring the implementation, if it is a
+	// Configurer, before the first step other than info.
+	configureOnce sync.Once
+	configureErr  error
+}
+
+// configure configures the implementation once, returning any error.
+func (s *grpcServer) configure() error {
+	s.configureOnce.Do(func() {
+		if c, ok := s.impl.(sdk.Configurer); ok {
+			s.configureErr = c.Configure()
+		}
+	})
+	return s.configureErr
+}
+
+func (s *grpcServer) Info(ctx context.Context, _ *proto.Empty) (*proto.InfoResponse, error) {
+	d, ok := s.impl.(Describer)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "benchmark does not implement Info")
+	}
+	info, err := d.Info(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, validate := s.impl.(Validator)
+	return &proto.InfoResponse{
+		Name:             info.Name,
+		Version:          info.Version,
+		SchedulerVersion: info.SchedulerVersion,
+		RequiredEnv:      info.RequiredEnv,
+		Metrics:          info.Metrics,
+		ExpectedTasks:    int64(info.ExpectedTasks),
+		Validate:         validate,
+	}, nil
+}
+
+func (s *grpcServer) Setup(ctx context.Context, _ *proto.Empty) (*proto.Empty, error) {
+	if err := s.configure(); err != nil {
+		return nil, err
+	}
+	return &proto.Empty{}, s.impl.Setup(ctx)
+}
+
+func (s *grpcServer) Run(ctx context.Context, _ *proto.Empty) (*proto.Empty, error) {
+	if err := s.configure(); err != nil {
+		return nil, err
+	}
+	return &proto.Empty{}, s.impl.Run(ctx)
+}
+
+func (s *grpcServer) Status(_ *proto.Empty, stream proto.Benchmark_StatusServer) error {
+	if err := s.configure(); err != nil {
+		return err
+	}
+	return s.impl.Status(stream.Context(), &streamWriter{stream: stream})
+}
+
+func (s *grpcServer) Validate(ctx context.Context, _ *proto.Empty) (*proto.ValidateResponse, error) {
+	v, ok := s.impl.(Validator)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "benchmark does not implement Validate")
+	}
+	if err := s.configure(); err != nil {
+		return nil, err
+	}
+	out, err := v.Validate(ctx)
+	if err != nil {
+		if out != "" && out[len(out)-1] != '\n' {
+			out += "\n"
+		}
+		return &proto.ValidateResponse{Valid: false, Output: out + err.Error() + "\n"}, nil
+	}
+	return &proto.ValidateResponse{Valid: true, Output: out}, nil
+}
+
+func (s *grpcServer) Teardown(ctx context.Context, _ *proto.Empty) (*proto.Empty, error) {
+	if err := s.configure(); err != nil {
+		return nil, err
+	}
+	return &proto.Empty{}, s.impl.Teardown(ctx)
+}
+
+// streamWriter sends status updates over the stream of the Status RPC.
+type streamWriter struct {
+	stream proto.Benchmark_StatusServer
+}
+
+func (w *streamWriter) Update(metric string, value float64, t time.Time) error {
+	return w.UpdateKind(metric, sdk.KindGauge, value, t)
+}
+
+func (w *streamWriter) UpdateKind(metric string, kind sdk.Kind, value float64, t time.Time) error {
+	return w.UpdateMetric(&sdk.Metric{Name: metric, Kind: kind}, value, t)
+}
+
+func (w *streamWriter) UpdateMetric(m *sdk.Metric, value float64, t time.Time) error {
+	var ts int64
+	if !t.IsZero() {
+		ts = t.UnixNano()
+	}
+	return w.stream.Send(&proto.StatusUpdate{
+		Metric:    m.Name,
+		Value:     value,
+		Timestamp: ts,
+		Kind:      string(m.Kind),
+		Labels:    m.Labels,
+		Unit:      m.Unit,
+	})
+}
+
+func (w *streamWriter) Event(name, message string, t time.Time) error {
+	var ts int64
+	if !t.IsZero() {
+		ts = t.UnixNano()
+	}
+	return w.stream.Send(&proto.StatusUpdate{
+		Metric:    name,
+		Timestamp: ts,
+		Message:   message,
+	})
+}
@@ -0,0 +1,77 @@
+// Package plugin allows test implementations written in Go to be served to
+// the benchmark runner as plugins over gRPC, rather than implementing the
+// fork/exec sub-command protocol. An implementation satisfies the Benchmark
+// interface and calls Serve from its main function:
+//
+//	func main() {
+//		plugin.Serve(&myBenchmark{})
+//	}
+//
+// The runner launches the plugin once and drives it through each step of
+// the benchmark, so an implementation may keep state in memory between
+// steps.
+package plugin
+
+import (
+	"os"
+
+	"github.com/hashicorp/go-hclog"
+	goplugin "github.com/hashicorp/go-plugin"
+	"github.com/hashicorp/schedbench/sdk"
+)
+
+// Handshake is used by the runner and plugins to verify they speak the same
+// protocol. It is not a security measure.
+var Handshake = goplugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "SCHEDBENCH_PLUGIN",
+	MagicCookieValue: "d6b3c1e0-schedbench-benchmark",
+}
+
+// pluginName is the name the benchmark is served under.
+const pluginName = "benchmark"
+
+// Benchmark is implemented by test implementations served as plugins. It is
+// the same interface as used by the sdk package, so an implementation may be
+// served either as a plugin or as an executable implementing the
+// sub-commands. The context of each step is canceled if the step times out
+// or the runner is interrupted.
+type Benchmark = sdk.Benchmark
+
+// StatusWriter receives the status updates of a benchmark. A zero time
+// means the update is timestamped by the runner when it is received.
+type StatusWriter = sdk.StatusWriter
+
+// Kind is the kind of a metric, as reported to StatusWriter.UpdateKind.
+type Kind = sdk.Kind
+
+// Metric describes the series of a metric, along with its labels and unit,
+// as reported to StatusWriter.UpdateMetric.
+type Metric = sdk.Metric
+
+// Info describes a test implementation. Its Validate field is set by Serve.
+type Info = sdk.Info
+
+// Describer and Validator may optionally be implemented by a Benchmark, to
+// support the info and validate sub-commands respectively.
+type (
+	Describer = sdk.Describer
+	Validator = sdk.Validator
+)
+
+// Serve serves the benchmark as a plugin. It should be called from the main
+// function of the plugin, and blocks until the runner is done with it.
+func Serve(impl Benchmark) {
+	goplugin.Serve(&goplugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins: goplugin.PluginSet{
+			pluginName: &GRPCPlugin{Impl: impl},
+		},
+		GRPCServer: goplugin.DefaultGRPCServer,
+		Logger: hclog.New(&hclog.LoggerOptions{
+			Name:   "plugin",
+			Level:  hclog.Error,
+			Output: os.Stderr,
+		}),
+	})
+}
@@ -0,0 +1,407 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: benchmark.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_benchmark_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmark_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_benchmark_proto_rawDescGZIP(), []int{0}
+}
+
+type InfoResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Version          string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	SchedulerVersion string                 `protobuf:"bytes,3,opt,name=scheduler_version,json=schedulerVersion,proto3" json:"scheduler_version,omitempty"`
+	RequiredEnv      []string               `protobuf:"bytes,4,rep,name=required_env,json=requiredEnv,proto3" json:"required_env,omitempty"`
+	Metrics          []string               `protobuf:"bytes,5,rep,name=metrics,proto3" json:"metrics,omitempty"`
+	ExpectedTasks    int64                  `protobuf:"varint,6,opt,name=expected_tasks,json=expectedTasks,proto3" json:"expected_tasks,omitempty"`
+	Validate         bool                   `protobuf:"varint,7,opt,name=validate,proto3" json:"validate,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *InfoResponse) Reset() {
+	*x = InfoResponse{}
+	mi := &file_benchmark_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InfoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InfoResponse) ProtoMessage() {}
+
+func (x *InfoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmark_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InfoResponse.ProtoReflect.Descriptor instead.
+func (*InfoResponse) Descriptor() ([]byte, []int) {
+	return file_benchmark_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *InfoResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetSchedulerVersion() string {
+	if x != nil {
+		return x.SchedulerVersion
+	}
+	return ""
+}
+
+func (x *InfoResponse) GetRequiredEnv() []string {
+	if x != nil {
+		return x.RequiredEnv
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetMetrics() []string {
+	if x != nil {
+		return x.Metrics
+	}
+	return nil
+}
+
+func (x *InfoResponse) GetExpectedTasks() int64 {
+	if x != nil {
+		return x.ExpectedTasks
+	}
+	return 0
+}
+
+func (x *InfoResponse) GetValidate() bool {
+	if x != nil {
+		return x.Validate
+	}
+	return false
+}
+
+type StatusUpdate struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Metric string                 `protobuf:"bytes,1,opt,name=metric,proto3" json:"metric,omitempty"`
+	Value  float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	// timestamp is the Unix time of the update in nanoseconds. If zero, the
+	// update is timestamped when it is received.
+	Timestamp int64 `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// kind is the kind of the metric, such as "counter". If empty, the
+	// metric is a gauge.
+	Kind string `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"`
+	// message is set if the update is an event rather than an update of a
+	// metric, in which case metric optionally names the event, and value and
+	// kind are unset.
+	Message string `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	// labels are the dimensions of the series of the metric, such as the
+	// node class tasks are running on.
+	Labels map[string]string `protobuf:"bytes,6,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// unit is the unit the metric is measured in, such as "ms".
+	Unit          string `protobuf:"bytes,7,opt,name=unit,proto3" json:"unit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatusUpdate) Reset() {
+	*x = StatusUpdate{}
+	mi := &file_benchmark_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatusUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusUpdate) ProtoMessage() {}
+
+func (x *StatusUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmark_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusUpdate.ProtoReflect.Descriptor instead.
+func (*StatusUpdate) Descriptor() ([]byte, []int) {
+	return file_benchmark_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *StatusUpdate) GetMetric() string {
+	if x != nil {
+		return x.Metric
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *StatusUpdate) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *StatusUpdate) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *StatusUpdate) GetUnit() string {
+	if x != nil {
+		return x.Unit
+	}
+	return ""
+}
+
+type ValidateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// valid is false if the benchmark was found to be invalid, in which case
+	// output should explain why.
+	Valid         bool   `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	Output        string `protobuf:"bytes,2,opt,name=output,proto3" json:"output,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateResponse) Reset() {
+	*x = ValidateResponse{}
+	mi := &file_benchmark_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateResponse) ProtoMessage() {}
+
+func (x *ValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_benchmark_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateResponse.ProtoReflect.Descriptor instead.
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return file_benchmark_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ValidateResponse) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *ValidateResponse) GetOutput() string {
+	if x != nil {
+		return x.Output
+	}
+	return ""
+}
+
+var File_benchmark_proto protoreflect.FileDescriptor
+
+const file_benchmark_proto_rawDesc = "" +
+	"\n" +
+	"\x0fbenchmark.proto\x12\x11schedbench.plugin\"\a\n" +
+	"\x05Empty\"\xe9\x01\n" +
+	"\fInfoResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12+\n" +
+	"\x11scheduler_version\x18\x03 \x01(\tR\x10schedulerVersion\x12!\n" +
+	"\frequired_env\x18\x04 \x03(\tR\vrequiredEnv\x12\x18\n" +
+	"\ametrics\x18\x05 \x03(\tR\ametrics\x12%\n" +
+	"\x0eexpected_tasks\x18\x06 \x01(\x03R\rexpectedTasks\x12\x1a\n" +
+	"\bvalidate\x18\a \x01(\bR\bvalidate\"\x9c\x02\n" +
+	"\fStatusUpdate\x12\x16\n" +
+	"\x06metric\x18\x01 \x01(\tR\x06metric\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12\x1c\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12\x12\n" +
+	"\x04kind\x18\x04 \x01(\tR\x04kind\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage\x12C\n" +
+	"\x06labels\x18\x06 \x03(\v2+.schedbench.plugin.StatusUpdate.LabelsEntryR\x06labels\x12\x12\n" +
+	"\x04unit\x18\a \x01(\tR\x04unit\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"@\n" +
+	"\x10ValidateResponse\x12\x14\n" +
+	"\x05valid\x18\x01 \x01(\bR\x05valid\x12\x16\n" +
+	"\x06output\x18\x02 \x01(\tR\x06output2\x98\x03\n" +
+	"\tBenchmark\x12A\n" +
+	"\x04Info\x12\x18.schedbench.plugin.Empty\x1a\x1f.schedbench.plugin.InfoResponse\x12;\n" +
+	"\x05Setup\x12\x18.schedbench.plugin.Empty\x1a\x18.schedbench.plugin.Empty\x129\n" +
+	"\x03Run\x12\x18.schedbench.plugin.Empty\x1a\x18.schedbench.plugin.Empty\x12E\n" +
+	"\x06Status\x12\x18.schedbench.plugin.Empty\x1a\x1f.schedbench.plugin.StatusUpdate0\x01\x12I\n" +
+	"\bValidate\x12\x18.schedbench.plugin.Empty\x1a#.schedbench.plugin.ValidateResponse\x12>\n" +
+	"\bTeardown\x12\x18.schedbench.plugin.Empty\x1a\x18.schedbench.plugin.EmptyB.Z,github.com/hashicorp/schedbench/plugin/protob\x06proto3"
+
+var (
+	file_benchmark_proto_rawDescOnce sync.Once
+	file_benchmark_proto_rawDescData []byte
+)
+
+func file_benchmark_proto_rawDescGZIP() []byte {
+	file_benchmark_proto_rawDescOnce.Do(func() {
+		file_benchmark_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_benchmark_proto_rawDesc), len(file_benchmark_proto_rawDesc)))
+	})
+	return file_benchmark_proto_rawDescData
+}
+
+var file_benchmark_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_benchmark_proto_goTypes = []any{
+	(*Empty)(nil),            // 0: schedbench.plugin.Empty
+	(*InfoResponse)(nil),     // 1: schedbench.plugin.InfoResponse
+	(*StatusUpdate)(nil),     // 2: schedbench.plugin.StatusUpdate
+	(*ValidateResponse)(nil), // 3: schedbench.plugin.ValidateResponse
+	nil,                      // 4: schedbench.plugin.StatusUpdate.LabelsEntry
+}
+var file_benchmark_proto_depIdxs = []int32{
+	4, // 0: schedbench.plugin.StatusUpdate.labels:type_name -> schedbench.plugin.StatusUpdate.LabelsEntry
+	0, // 1: schedbench.plugin.Benchmark.Info:input_type -> schedbench.plugin.Empty
+	0, // 2: schedbench.plugin.Benchmark.Setup:input_type -> schedbench.plugin.Empty
+	0, // 3: schedbench.plugin.Benchmark.Run:input_type -> schedbench.plugin.Empty
+	0, // 4: schedbench.plugin.Benchmark.Status:input_type -> schedbench.plugin.Empty
+	0, // 5: schedbench.plugin.Benchmark.Validate:input_type -> schedbench.plugin.Empty
+	0, // 6: schedbench.plugin.Benchmark.Teardown:input_type -> schedbench.plugin.Empty
+	1, // 7: schedbench.plugin.Benchmark.Info:output_type -> schedbench.plugin.InfoResponse
+	0, // 8: schedbench.plugin.Benchmark.Setup:output_type -> schedbench.plugin.Empty
+	0, // 9: schedbench.plugin.Benchmark.Run:output_type -> schedbench.plugin.Empty
+	2, // 10: schedbench.plugin.Benchmark.Status:output_type -> schedbench.plugin.StatusUpdate
+	3, // 11: schedbench.plugin.Benchmark.Validate:output_type -> schedbench.plugin.ValidateResponse
+	0, // 12: schedbench.plugin.Benchmark.Teardown:output_type -> schedbench.plugin.Empty
+	7, // [7:13] is the sub-list for method output_type
+	1, // [1:7] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_benchmark_proto_init() }
+func file_benchmark_proto_init() {
+	if File_benchmark_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_benchmark_proto_rawDesc), len(file_benchmark_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_benchmark_proto_goTypes,
+		DependencyIndexes: file_benchmark_proto_depIdxs,
+		MessageInfos:      file_benchmark_proto_msgTypes,
+	}.Build()
+	File_benchmark_proto = out.File
+	file_benchmark_proto_goTypes = nil
+	file_benchmark_proto_depIdxs = nil
+}
@@ -0,0 +1,339 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: benchmark.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Benchmark_Info_FullMethodName     = "/schedbench.plugin.Benchmark/Info"
+	Benchmark_Setup_FullMethodName    = "/schedbench.plugin.Benchmark/Setup"
+	Benchmark_Run_FullMethodName      = "/schedbench.plugin.Benchmark/Run"
+	Benchmark_Status_FullMethodName   = "/schedbench.plugin.Benchmark/Status"
+	Benchmark_Validate_FullMethodName = "/schedbench.plugin.Benchmark/Validate"
+	Benchmark_Teardown_FullMethodName = "/schedbench.plugin.Benchmark/Teardown"
+)
+
+// BenchmarkClient is the client API for Benchmark service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Benchmark is the service served by a test implementation plugin. Each RPC
+// corresponds to a sub-command of the fork/exec protocol. The plugin process
+// lives for the duration of the benchmark, so state may be kept in memory
+// between RPCs.
+type BenchmarkClient interface {
+	// Info describes the implementation. It is optional, and plugins which do
+	// not support it return UNIMPLEMENTED.
+	Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*InfoResponse, error)
+	Setup(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	Run(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+	// Status streams updates of the metrics of the benchmark until all work
+	// has completed.
+	Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusUpdate], error)
+	// Validate checks the correctness of the benchmark once status completes.
+	// It is optional, and plugins which do not support it return
+	// UNIMPLEMENTED.
+	Validate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ValidateResponse, error)
+	Teardown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type benchmarkClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBenchmarkClient(cc grpc.ClientConnInterface) BenchmarkClient {
+	return &benchmarkClient{cc}
+}
+
+func (c *benchmarkClient) Info(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*InfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InfoResponse)
+	err := c.cc.Invoke(ctx, Benchmark_Info_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *benchmarkClient) Setup(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Benchmark_Setup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *benchmarkClient) Run(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Benchmark_Run_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *benchmarkClient) Status(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Benchmark_ServiceDesc.Streams[0], Benchmark_Status_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Empty, StatusUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Benchmark_StatusClient = grpc.ServerStreamingClient[StatusUpdate]
+
+func (c *benchmarkClient) Validate(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, Benchmark_Validate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *benchmarkClient) Teardown(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*Empty, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, Benchmark_Teardown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BenchmarkServer is the server API for Benchmark service.
+// All implementations must embed UnimplementedBenchmarkServer
+// for forward compatibility.
+//
+// Benchmark is the service served by a test implementation plugin. Each RPC
+// corresponds to a sub-command of the fork/exec protocol. The plugin process
+// lives for the duration of the benchmark, so state may be kept in memory
+// between RPCs.
+type BenchmarkServer interface {
+	// Info describes the implementation. It is optional, and plugins which do
+	// not support it return UNIMPLEMENTED.
+	Info(context.Context, *Empty) (*InfoResponse, error)
+	Setup(context.Context, *Empty) (*Empty, error)
+	Run(context.Context, *Empty) (*Empty, error)
+	// Status streams updates of the metrics of the benchmark until all work
+	// has completed.
+	Status(*Empty, grpc.ServerStreamingServer[StatusUpdate]) error
+	// Validate checks the correctness of the benchmark once status completes.
+	// It is optional, and plugins which do not support it return
+	// UNIMPLEMENTED.
+	Validate(context.Context, *Empty) (*ValidateResponse, error)
+	Teardown(context.Context, *Empty) (*Empty, error)
+	mustEmbedUnimplementedBenchmarkServer()
+}
+
+// UnimplementedBenchmarkServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedBenchmarkServer struct{}
+
+func (UnimplementedBenchmarkServer) Info(context.Context, *Empty) (*InfoResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Info not implemented")
+}
+func (UnimplementedBenchmarkServer) Setup(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Setup not implemented")
+}
+func (UnimplementedBenchmarkServer) Run(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Run not implemented")
+}
+func (UnimplementedBenchmarkServer) Status(*Empty, grpc.ServerStreamingServer[StatusUpdate]) error {
+	return status.Error(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedBenchmarkServer) Validate(context.Context, *Empty) (*ValidateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedBenchmarkServer) Teardown(context.Context, *Empty) (*Empty, error) {
+	return nil, status.Error(codes.Unimplemented, "method Teardown not implemented")
+}
+func (UnimplementedBenchmarkServer) mustEmbedUnimplementedBenchmarkServer() {}
+func (UnimplementedBenchmarkServer) testEmbeddedByValue()                   {}
+
+// UnsafeBenchmarkServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BenchmarkServer will
+// result in compilation errors.
+type UnsafeBenchmarkServer interface {
+	mustEmbedUnimplementedBenchmarkServer()
+}
+
+func RegisterBenchmarkServer(s grpc.ServiceRegistrar, srv BenchmarkServer) {
+	// If the following call panics, it indicates UnimplementedBenchmarkServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Benchmark_ServiceDesc, srv)
+}
+
+func _Benchmark_Info_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchmarkServer).Info(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Benchmark_Info_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BenchmarkServer).Info(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Benchmark_Setup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchmarkServer).Setup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Benchmark_Setup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BenchmarkServer).Setup(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Benchmark_Run_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchmarkServer).Run(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Benchmark_Run_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BenchmarkServer).Run(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Benchmark_Status_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BenchmarkServer).Status(m, &grpc.GenericServerStream[Empty, StatusUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Benchmark_StatusServer = grpc.ServerStreamingServer[StatusUpdate]
+
+func _Benchmark_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchmarkServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Benchmark_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BenchmarkServer).Validate(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Benchmark_Teardown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BenchmarkServer).Teardown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Benchmark_Teardown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BenchmarkServer).Teardown(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Benchmark_ServiceDesc is the grpc.ServiceDesc for Benchmark service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Benchmark_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "schedbench.plugin.Benchmark",
+	HandlerType: (*BenchmarkServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Info",
+			Handler:    _Benchmark_Info_Handler,
+		},
+		{
+			MethodName: "Setup",
+			Handler:    _Benchmark_Setup_Handler,
+		},
+		{
+			MethodName: "Run",
+			Handler:    _Benchmark_Run_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _Benchmark_Validate_Handler,
+		},
+		{
+			MethodName: "Teardown",
+			Handler:    _Benchmark_Teardown_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Status",
+			Handler:       _Benchmark_Status_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "benchmark.proto",
+}
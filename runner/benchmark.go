@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// Exit codes returned by the runner.
+const (
+	exitCodeOK      = 0
+	exitCodeFailed  = 1
+	exitCodeInvalid = 2
+	exitCodeTimeout = 124
+
+	// exitCodeSignal is added to the number of the signal which interrupted
+	// the benchmark, following the shell convention.
+	exitCodeSignal = 128
+)
+
+// result holds the outcome of a single execution of a benchmark.
+type result struct {
+	*bench.Result
+
+	// totals holds the total of each metric across the series which pass
+	// the label filter, and samples every sample of each distribution
+	// which passes it. The summary is derived from both.
+	totals  bench.Metrics
+	samples bench.Samples
+
+	// summary holds the metrics derived from a complete result.
+	summary map[string]float64
+}
+
+// suffix returns the suffix added to the names of the result files when
+// the result is not complete.
+func (r *result) suffix() string {
+	switch {
+	case r.Err != nil || r.Interrupted != nil:
+		return ".incomplete"
+	case r.Invalid != nil:
+		return ".invalid"
+	}
+	return ""
+}
+
+// exitCode returns the exit code of the runner reflecting the first failure
+// of the benchmark, if any.
+func (r *result) exitCode() int {
+	switch {
+	case r.Interrupted != nil:
+		return signalExitCode(r.Interrupted)
+	case r.TimedOut():
+		return exitCodeTimeout
+	case r.Err != nil:
+		return exitCodeFailed
+	case r.Invalid != nil:
+		return exitCodeInvalid
+	case r.TeardownErr != nil:
+		return exitCodeFailed
+	}
+	return exitCodeOK
+}
+
+// runBenchmark executes every step of the benchmark described by the
+// config, followed by the teardown. The results are written to files named
+// after resultName within the output directory, unless it is empty. If
+// enabled, the output of each step is also captured to log files named
+// after the result, such as "logs/result/run.stderr.log".
+func runBenchmark(conf *config, sup *supervisor, resultName string) *result {
+	bc := conf.benchConfig(sup)
+	if resultName != "" {
+		name := filepath.Join(conf.dir, resultName)
+		if conf.logs {
+			bc.LogDir = filepath.Join(conf.dir, "logs", resultName)
+		}
+
+		// Flush whatever was collected before running the teardown, which
+		// may take a long time. A failed benchmark still yields a partial
+		// result.
+		bc.BeforeTeardown = func(res *bench.Result) {
+			if res.Metrics == nil {
+				return
+			}
+			suffix := (&result{Result: res}).suffix()
+			if suffix == ".incomplete" {
+				log.Printf("[WARN] runner: benchmark did not complete; result is partial")
+			}
+			if err := writeResult(name, conf.series(res.Metrics), suffix); err != nil {
+				log.Printf("[ERR] runner: failed writing result: %v", err)
+			}
+			if len(res.Samples) != 0 {
+				if err := writeSamples(name, conf.sampleSeries(res.Samples), suffix); err != nil {
+					log.Printf("[ERR] runner: failed writing samples: %v", err)
+				}
+			}
+			if len(res.Events) != 0 {
+				if err := writeEvents(name, res.Events, suffix); err != nil {
+					log.Printf("[ERR] runner: failed writing events: %v", err)
+				}
+			}
+			if res.Validation != nil {
+				path := name + ".validate.txt"
+				if err := ioutil.WriteFile(path, res.Validation, 0644); err != nil {
+					log.Printf("[ERR] runner: failed writing validation output: %v", err)
+				}
+			}
+		}
+	}
+	res := &result{Result: bench.New(bc).Run(context.Background())}
+	res.totals = res.Metrics.Filter(conf.filter).Group(nil)
+	res.samples = res.Samples.Filter(conf.filter).Group(nil)
+
+	// Record when each step started and ended
+	if err := conf.record.addExecution(resultName, res.Result); err != nil {
+		log.Printf("[ERR] runner: %v", err)
+	}
+	if resultName != "" && len(res.Timeline) != 0 {
+		path := filepath.Join(conf.dir, resultName+".timeline.csv")
+		if err := writeTimeline(path, res.Timeline); err != nil {
+			log.Printf("[ERR] runner: failed writing timeline: %v", err)
+		}
+	}
+	return res
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// checkMaxLineErrors is the number of invalid lines of status output which
+// are reported individually before the rest are only counted.
+const checkMaxLineErrors = 10
+
+// checkUnknownPhase is the sub-command used to check that an implementation
+// rejects sub-commands it does not support.
+const checkUnknownPhase = "bench-runner-check-unknown"
+
+// checkReport prints the outcome of each conformance check as it is made,
+// and counts the warnings and failures. It is safe for concurrent use.
+type checkReport struct {
+	out      io.Writer
+	passes   int
+	warnings int
+	failures int
+	lock     sync.Mutex
+}
+
+func (r *checkReport) pass(step, format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.passes++
+	r.print("PASS", step, format, args...)
+}
+
+func (r *checkReport) warn(step, format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.warnings++
+	r.print("WARN", step, format, args...)
+}
+
+func (r *checkReport) fail(step, format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.failures++
+	r.print("FAIL", step, format, args...)
+}
+
+func (r *checkReport) print(outcome, step, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	fmt.Fprintf(r.out, "%s  %-8s  %s\n", outcome, step,
+		strings.Replace(msg, "\n", "\n                ", -1))
+}
+
+// checker exercises each sub-command of a test implementation and reports
+// whether it conforms to the protocol expected by the runner.
+type checker struct {
+	conf   *config
+	sup    *supervisor
+	runner *bench.Runner
+	report *checkReport
+
+	// statusCh is closed once the status step has started.
+	statusCh chan struct{}
+
+	// start is when the check began, used to judge whether timestamps in
+	// the status output are plausible.
+	start time.Time
+}
+
+// runCheck implements the check command, which checks that the test
+// implementation at the given path conforms to the protocol. Returns the
+// exit code of the runner: 0 if every check passed, allowing warnings.
+func runCheck(args []string) int {
+	var timeout time.Duration
+	flags := flag.NewFlagSet("bench-runner check", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	var env []string
+	flags.DurationVar(&timeout, "timeout", 10*time.Minute, "")
+	flags.Var((*envFlag)(&env), "env", "")
+	if err := flags.Parse(args); err != nil {
+		return exitCodeFailed
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitCodeFailed
+	}
+	path := flags.Arg(0)
+	if err := checkExecutable(path); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return exitCodeFailed
+	}
+
+	sup := newSupervisor()
+	defer sup.stop()
+	c := &checker{
+		conf: &config{
+			path: path,
+			env:  env,
+			timeouts: bench.Timeouts{
+				Setup:    timeout,
+				Run:      timeout,
+				Status:   timeout,
+				Validate: timeout,
+				Teardown: timeout,
+			},
+		},
+		sup:      sup,
+		report:   &checkReport{out: os.Stdout},
+		statusCh: make(chan struct{}),
+		start:    time.Now(),
+	}
+	bc := c.conf.benchConfig(sup)
+	bc.OnStepStart = c.stepStarted
+	c.runner = bench.New(bc)
+	c.run()
+
+	r := c.report
+	fmt.Fprintf(r.out, "\n%d passed, %d warnings, %d failed\n", r.passes, r.warnings, r.failures)
+	switch {
+	case sup.Interrupted() != nil:
+		return signalExitCode(sup.Interrupted())
+	case r.failures != 0:
+		return exitCodeFailed
+	}
+	return exitCodeOK
+}
+
+// run executes each check in turn. The teardown is executed once setup has
+// been attempted, regardless of the outcome of the other checks.
+func (c *checker) run() {
+	c.checkUnknown()
+	info, ok := c.checkInfo()
+	if !ok || c.sup.Interrupted() != nil {
+		return
+	}
+
+	if c.checkStep("setup", c.conf.timeouts.Setup) {
+		if c.checkRun(info) && info != nil && info.Validate {
+			c.checkStep("validate", c.conf.timeouts.Validate)
+		}
+	}
+	c.checkStep("teardown", c.conf.timeouts.Teardown)
+}
+
+// stepStarted is called as each step starts.
+func (c *checker) stepStarted(phase string) {
+	if phase == "status" {
+		close(c.statusCh)
+	}
+}
+
+// output executes a step to completion and returns its stdout.
+func (c *checker) output(phase string, timeout time.Duration) (*bench.Step, []byte, error) {
+	var out bytes.Buffer
+	step, err := c.runner.Exec(context.Background(), phase, timeout, &out)
+	return step, out.Bytes(), err
+}
+
+// checkUnknown checks that an unknown sub-command is rejected, which is how
+// the runner detects that optional sub-commands are not supported.
+func (c *checker) checkUnknown() {
+	_, out, err := c.output(checkUnknownPhase, c.conf.timeouts.Setup)
+	switch {
+	case err != nil && err != bench.ErrInterrupted:
+		c.report.pass("unknown", "unknown sub-commands exit non-zero")
+	case err == nil && len(bytes.TrimSpace(out)) == 0:
+		c.report.pass("unknown", "unknown sub-commands print nothing to stdout")
+	case err == nil:
+		c.report.warn("unknown", "unknown sub-commands exit 0 and print to stdout; "+
+			"optional sub-commands should exit non-zero if not supported")
+	}
+}
+
+// checkInfo checks the output of the optional info sub-command. Returns the
+// metadata if it is supported, and false if the benchmark can not run.
+func (c *checker) checkInfo() (*bench.Info, bool) {
+	_, out, err := c.output("info", bench.InfoTimeout)
+	if err == bench.ErrInterrupted {
+		return nil, false
+	}
+	if err != nil || len(bytes.TrimSpace(out)) == 0 {
+		c.report.pass("info", "not supported (optional)")
+		return nil, true
+	}
+
+	info := &bench.Info{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(info); err != nil {
+		if json.Unmarshal(out, info) != nil {
+			c.report.fail("info", "output is not a valid JSON object: %v\nStdout: %s", err, out)
+			return nil, true
+		}
+		c.report.warn("info", "output has fields unknown to the runner: %v", err)
+	}
+	if info.Name == "" || info.Version == "" {
+		c.report.warn("info", "should give a name and version")
+	}
+	if len(info.Metrics) == 0 {
+		c.report.warn("info", "should list the metrics it emits")
+	} else if !containsString(info.Metrics, "running") {
+		c.report.fail("info", "metrics must include the reserved 'running' metric")
+	}
+	if err := validateInfo(info, c.conf); err != nil {
+		c.report.fail("info", "%v", err)
+		return info, false
+	}
+	c.report.pass("info", "implementation %q version %q", info.Name, info.Version)
+	return info, true
+}
+
+// checkStep checks that a step which does not produce status exits 0.
+func (c *checker) checkStep(phase string, timeout time.Duration) bool {
+	step, out, err := c.output(phase, timeout)
+	if err != nil {
+		c.report.fail(phase, "%v", &bench.StepError{Phase: phase, Err: err, Stdout: out})
+		return false
+	}
+	c.report.pass(phase, "exited with code 0 in %s", roundDuration(step.Duration()))
+	return true
+}
+
+// checkRun checks the run and status steps, which execute concurrently,
+// strictly validating the status output. If the metadata gives the number
+// of tasks expected, 'running' must reach it by the time status exits.
+func (c *checker) checkRun(info *bench.Info) bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	outBuf, w := io.Pipe()
+	var step *bench.Step
+	var err error
+	statusDone := make(chan struct{})
+	go func() {
+		defer close(statusDone)
+		step, err = c.runner.ExecStatus(ctx, c.conf.timeouts.Status, w)
+		w.Close()
+	}()
+	select {
+	case <-c.statusCh:
+	case <-statusDone:
+		if step == nil {
+			c.report.fail("status", "%v", &bench.StepError{Phase: "status", Err: err})
+			return false
+		}
+	}
+	doneCh := make(chan struct{})
+	var running float64
+	var reported bool
+	go func() {
+		defer close(doneCh)
+		running, reported = c.checkStatusOutput(outBuf)
+	}()
+
+	ok := c.checkStep("run", c.conf.timeouts.Run)
+	if !ok {
+		cancel()
+	}
+	<-doneCh
+	<-statusDone
+	if err != nil {
+		if ok {
+			c.report.fail("status", "%v", &bench.StepError{Phase: "status", Err: err})
+		}
+		return false
+	}
+	c.report.pass("status", "exited with code 0 in %s", roundDuration(step.Duration()))
+	if info != nil && info.ExpectedTasks != 0 && reported {
+		if running != float64(info.ExpectedTasks) {
+			c.report.fail("status", "'running' ended at %v, but %d tasks were expected",
+				running, info.ExpectedTasks)
+			return false
+		}
+		c.report.pass("status", "'running' reached the %d tasks expected", info.ExpectedTasks)
+	}
+	return ok
+}
+
+// checkStatusOutput validates every line of status output read from r.
+// Returns the final number of running tasks, and whether it was reported.
+func (c *checker) checkStatusOutput(r io.Reader) (float64, bool) {
+	var lines, invalid, untimed, events int
+	var decreases int
+	lastRunning := make(map[string]float64)
+	lastTimes := make(map[string]time.Time)
+	kinds := make(map[string]bench.Kind)
+	outOfOrder := make(map[string]struct{})
+
+	lineErr := func(format string, args ...interface{}) {
+		invalid++
+		if invalid <= checkMaxLineErrors {
+			c.report.fail("status", "line %d: %s", lines, fmt.Sprintf(format, args...))
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines++
+		line := scanner.Text()
+		now := time.Now()
+		update, event, err := bench.ParseStatusLine(line, now)
+		if err != nil {
+			lineErr("%v", err)
+			continue
+		}
+
+		// Timestamps must be Unix time in nanoseconds, so anything far from
+		// the present is likely in the wrong unit. Lines without one are
+		// timestamped with now.
+		var t time.Time
+		if event != nil {
+			t = event.Time
+		} else {
+			t = update.Time
+		}
+		if t.Equal(now) {
+			untimed++
+		} else if t.Before(c.start.Add(-24*time.Hour)) || t.After(now.Add(time.Hour)) {
+			lineErr("timestamp %d is not Unix time in nanoseconds near the present", t.UnixNano())
+			continue
+		}
+
+		if event != nil {
+			if strings.TrimSpace(event.Message) == "" {
+				lineErr("event message is blank in %q", line)
+			}
+			events++
+			continue
+		}
+		switch {
+		case update.Metric == "":
+			lineErr("metric name is empty in %q", line)
+			continue
+		case strings.TrimSpace(update.Metric) != update.Metric:
+			lineErr("metric name has surrounding whitespace in %q", line)
+			continue
+		case math.IsNaN(update.Value) || math.IsInf(update.Value, 0):
+			lineErr("metric value is not finite in %q", line)
+			continue
+		}
+
+		series := bench.SeriesName(update.Metric, update.Labels)
+		if kind, ok := kinds[series]; ok && kind != update.Kind {
+			lineErr("%q was first reported as a %s, not a %s", series, kind, update.Kind)
+			continue
+		}
+		kinds[series] = update.Kind
+		if last, ok := lastTimes[series]; ok && update.Time.Before(last) {
+			outOfOrder[series] = struct{}{}
+		}
+		lastTimes[series] = update.Time
+
+		// Each series of running tasks must only increase. Its increments
+		// may be reported as deltas, which then must not be negative.
+		if update.Metric == "running" {
+			value := update.Value
+			switch update.Kind {
+			case bench.KindSample:
+				lineErr("'running' must not be reported as a %s", update.Kind)
+				continue
+			case bench.KindDelta:
+				if value < 0 {
+					decreases++
+				}
+				value += lastRunning[series]
+			}
+			if value < 0 || value != math.Trunc(value) {
+				lineErr("'running' must be a whole number of tasks, got %v", value)
+				continue
+			}
+			if last, ok := lastRunning[series]; ok && update.Kind != bench.KindDelta && value < last {
+				decreases++
+			}
+			lastRunning[series] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		c.report.fail("status", "failed reading output: %v", err)
+		io.Copy(ioutil.Discard, r)
+	}
+
+	if invalid > checkMaxLineErrors {
+		c.report.fail("status", "%d more invalid lines not shown", invalid-checkMaxLineErrors)
+	}
+	if lines == 0 {
+		c.report.fail("status", "no status output")
+		return 0, false
+	}
+	if invalid == 0 {
+		c.report.pass("status", "all %d lines of output are valid", lines)
+	}
+	if untimed != 0 {
+		c.report.warn("status", "%d of %d lines have no timestamp; the runner timestamps them "+
+			"on receipt, which is less accurate", untimed, lines)
+	}
+	for key := range outOfOrder {
+		c.report.warn("status", "timestamps of series %q are not in order", key)
+	}
+	if events != 0 {
+		c.report.pass("status", "%d events were emitted", events)
+	}
+	// The total running is the unlabeled series if it was emitted, and
+	// otherwise the sum of the labeled series.
+	var running float64
+	for _, value := range lastRunning {
+		running += value
+	}
+	if value, ok := lastRunning["running"]; ok {
+		running = value
+	}
+	switch {
+	case len(lastRunning) == 0:
+		c.report.fail("status", "the reserved 'running' metric was never emitted")
+	case decreases != 0:
+		c.report.warn("status", "'running' decreased %d time(s); it is expected to increase "+
+			"monotonically as tasks start", decreases)
+	default:
+		c.report.pass("status", "'running' is emitted and never decreases, reaching %v", running)
+	}
+	return running, len(lastRunning) != 0
+}
+
+// containsString returns whether the slice contains the string.
+func containsString(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// roundDuration rounds a duration to the millisecond for display.
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
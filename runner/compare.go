@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// point is the value of a metric at a number of milliseconds elapsed since
+// the start of the benchmark.
+type point struct {
+	elapsed int64
+	value   float64
+}
+
+// curve returns every recorded value of the named metric in time order.
+func curve(metrics map[int64]map[string]float64, name string) []point {
+	var points []point
+	for elapsed, events := range metrics {
+		if value, ok := events[name]; ok {
+			points = append(points, point{elapsed: elapsed, value: value})
+		}
+	}
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].elapsed < points[j].elapsed
+	})
+	return points
+}
+
+// meanCurve averages curves, treating each as a step function which holds
+// its last value. Before its first point, a curve is treated as zero.
+func meanCurve(curves [][]point) []point {
+	if len(curves) == 1 {
+		return curves[0]
+	}
+
+	// Collect the times at which any of the curves change
+	timesMap := make(map[int64]struct{})
+	for _, c := range curves {
+		for _, p := range c {
+			timesMap[p.elapsed] = struct{}{}
+		}
+	}
+	times := make([]int64, 0, len(timesMap))
+	for t := range timesMap {
+		times = append(times, t)
+	}
+	sort.Sort(Int64Sort(times))
+
+	// Walk every curve in step, averaging the values held at each time
+	mean := make([]point, len(times))
+	idx := make([]int, len(curves))
+	last := make([]float64, len(curves))
+	for i, t := range times {
+		var sum float64
+		for j, c := range curves {
+			for idx[j] < len(c) && c[idx[j]].elapsed <= t {
+				last[j] = c[idx[j]].value
+				idx[j]++
+			}
+			sum += last[j]
+		}
+		mean[i] = point{elapsed: t, value: sum / float64(len(curves))}
+	}
+	return mean
+}
+
+// implementationNames names each implementation after the base name of its
+// path, adding a numeric suffix to tell apart implementations with the same
+// base name.
+func implementationNames(paths []string) []string {
+	names := make([]string, len(paths))
+	seen := make(map[string]int, len(paths))
+	for i, path := range paths {
+		name := filepath.Base(path)
+		seen[name]++
+		if n := seen[name]; n > 1 {
+			name = fmt.Sprintf("%s-%d", name, n)
+		}
+		names[i] = name
+	}
+	return names
+}
+
+// comparison collects the results of several implementations of the same
+// benchmark so that they can be reported side by side.
+type comparison struct {
+	names   []string
+	results [][]*result
+}
+
+// add records the results of the named implementation. Implementations
+// without any complete results are left out of the comparison.
+func (c *comparison) add(name string, results []*result) {
+	if len(results) == 0 {
+		return
+	}
+	c.names = append(c.names, name)
+	c.results = append(c.results, results)
+}
+
+// write writes the comparison to the directory. The running curve of each
+// implementation, averaged across its iterations, is aligned on a common
+// timeline in comparison.csv. The derived metrics of each implementation are
+// written to comparison-summary.csv and printed, and both are combined into
+// an HTML report in comparison.html, on which the events of every iteration
+// of each implementation are overlaid. If the comparison is not complete, the
+// files are marked as incomplete in the same way as the results.
+func (c *comparison) write(dir string, complete bool) error {
+	if len(c.names) == 0 {
+		return nil
+	}
+	name := func(base, ext string) string {
+		if !complete {
+			base += ".incomplete"
+		}
+		return filepath.Join(dir, base+ext)
+	}
+
+	curves := make([][]point, len(c.results))
+	events := make([][]*bench.Event, len(c.results))
+	rows := make([][]string, len(c.results))
+	for i, results := range c.results {
+		running := make([][]point, len(results))
+		for j, res := range results {
+			running[j] = curve(res.totals, "running")
+			events[i] = append(events[i], res.Events...)
+		}
+		curves[i] = meanCurve(running)
+		rows[i] = summaryRow([]string{c.names[i]}, results)
+	}
+
+	path := name("comparison", ".csv")
+	if err := writeCurves(path, c.names, curves); err != nil {
+		return fmt.Errorf("failed writing comparison: %v", err)
+	}
+	log.Printf("[INFO] runner: comparison written to %s", path)
+
+	path = name("comparison-summary", ".csv")
+	if err := writeSummary(path, []string{"implementation"}, rows); err != nil {
+		return fmt.Errorf("failed writing comparison summary: %v", err)
+	}
+	log.Printf("[INFO] runner: comparison summary written to %s", path)
+
+	path = name("comparison", ".html")
+	header := append([]string{"implementation", "iterations"}, summaryMetrics...)
+	if err := writeReport(path, "Comparison of running tasks", c.names, curves, events, header, rows); err != nil {
+		return fmt.Errorf("failed writing comparison report: %v", err)
+	}
+	log.Printf("[INFO] runner: comparison report written to %s", path)
+	return nil
+}
+
+// writeCurves aligns the named curves on a common timeline and writes them
+// as CSV to the file at path. Each curve holds its last value at the times
+// where it has no point of its own.
+func writeCurves(path string, names []string, curves [][]point) error {
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write(append([]string{"elapsed_ms"}, names...))
+
+	timesMap := make(map[int64]struct{})
+	for _, c := range curves {
+		for _, p := range c {
+			timesMap[p.elapsed] = struct{}{}
+		}
+	}
+	times := make([]int64, 0, len(timesMap))
+	for t := range timesMap {
+		times = append(times, t)
+	}
+	sort.Sort(Int64Sort(times))
+
+	idx := make([]int, len(curves))
+	last := make([]float64, len(curves))
+	for _, t := range times {
+		records := make([]string, len(curves)+1)
+		records[0] = strconv.FormatInt(t, 10)
+		for i, c := range curves {
+			for idx[i] < len(c) && c[idx[i]].elapsed <= t {
+				last[i] = c[idx[i]].value
+				idx[i]++
+			}
+			records[i+1] = strconv.FormatFloat(last[i], 'f', -1, 64)
+		}
+		csvWriter.Write(records)
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// runCompare executes the benchmark once for each implementation, in
+// sequence, and writes a comparison of their results. The results of each
+// implementation are written to a sub-directory of the output directory
+// named after it, such as "bench-nomad-results". Execution stops at the
+// first failed implementation. Returns the exit code of the runner.
+func runCompare(conf *config, paths []string, sup *supervisor) int {
+	names := implementationNames(paths)
+	cmp := &comparison{}
+	code := exitCodeOK
+	for i, path := range paths {
+		if i > 0 {
+			if err := sup.Sleep(conf.cooldown); err != nil {
+				code = signalExitCode(sup.Interrupted())
+				break
+			}
+		}
+		log.Printf("[INFO] runner: starting implementation %q (%d of %d)",
+			names[i], i+1, len(paths))
+
+		impl := *conf
+		impl.path = path
+		impl.dir = filepath.Join(conf.dir, names[i]+"-results")
+		if err := os.MkdirAll(impl.dir, 0755); err != nil {
+			log.Printf("[ERR] runner: failed creating result directory: %v", err)
+			code = exitCodeFailed
+			break
+		}
+
+		var results []*result
+		code, results = runIterations(&impl, sup)
+		cmp.add(names[i], results)
+		if code != exitCodeOK {
+			break
+		}
+	}
+
+	if err := cmp.write(conf.dir, code == exitCodeOK); err != nil {
+		log.Printf("[ERR] runner: %v", err)
+		if code == exitCodeOK {
+			code = exitCodeFailed
+		}
+	}
+	return code
+}
@@ -0,0 +1,768 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// runnerBin and fakeBin are the paths of the runner and the scripted fake
+// implementation, and pluginBin that of the fake served as a Go plugin,
+// built once for every end-to-end test.
+var runnerBin, fakeBin, pluginBin string
+
+func TestMain(m *testing.M) {
+	os.Exit(testMain(m))
+}
+
+func testMain(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "bench-runner-e2e")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed creating build directory: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	runnerBin = filepath.Join(dir, "bench-runner")
+	fakeBin = filepath.Join(dir, "fake")
+	pluginBin = filepath.Join(dir, "plugin")
+	builds := map[string]string{
+		".":                 runnerBin,
+		"./testdata/fake":   fakeBin,
+		"./testdata/plugin": pluginBin,
+	}
+	for pkg, out := range builds {
+		cmd := exec.Command("go", "build", "-o", out, pkg)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed building %s: %v\n%s", pkg, err, output)
+			return 1
+		}
+	}
+	return m.Run()
+}
+
+// e2eRun is the outcome of an invocation of the runner.
+type e2eRun struct {
+	// dir is the working directory of the runner, which results are
+	// written to.
+	dir string
+
+	code   int
+	output string
+}
+
+// runRunner invokes the runner against the fake implementation, with the
+// fake scripted by the environment variables in env. The given args precede
+// the path of the fake.
+func runRunner(t *testing.T, env []string, args ...string) *e2eRun {
+	t.Helper()
+	return runRunnerIn(t, t.TempDir(), env, append(args, fakeBin)...)
+}
+
+// runRunnerIn invokes the runner with the args alone, within the directory,
+// with the fake scripted by the environment variables in env.
+func runRunnerIn(t *testing.T, dir string, env []string, args ...string) *e2eRun {
+	t.Helper()
+	cmd := exec.Command(runnerBin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "FAKE_LOG="+filepath.Join(dir, "calls.log"))
+	cmd.Env = append(cmd.Env, env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+
+	err := cmd.Run()
+	run := &e2eRun{dir: dir, output: out.String()}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		run.code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("failed running runner: %v", err)
+	}
+	return run
+}
+
+// calls returns the sub-commands the fake was invoked with, in order.
+func (r *e2eRun) calls(t *testing.T) []string {
+	t.Helper()
+	out, err := ioutil.ReadFile(filepath.Join(r.dir, "calls.log"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed reading calls: %v", err)
+	}
+	return strings.Fields(string(out))
+}
+
+// exists returns whether the file was written by the runner.
+func (r *e2eRun) exists(name string) bool {
+	_, err := os.Stat(filepath.Join(r.dir, name))
+	return err == nil
+}
+
+// readCSV reads a CSV file written by the runner.
+func (r *e2eRun) readCSV(t *testing.T, name string) [][]string {
+	t.Helper()
+	fh, err := os.Open(filepath.Join(r.dir, name))
+	if err != nil {
+		t.Fatalf("failed opening %s: %v\nOutput:\n%s", name, err, r.output)
+	}
+	defer fh.Close()
+	records, err := csv.NewReader(fh).ReadAll()
+	if err != nil {
+		t.Fatalf("failed parsing %s: %v", name, err)
+	}
+	return records
+}
+
+// assertCode fails the test if the runner did not exit with the code.
+func (r *e2eRun) assertCode(t *testing.T, code int) {
+	t.Helper()
+	if r.code != code {
+		t.Fatalf("expected exit code %d, got %d\nOutput:\n%s", code, r.code, r.output)
+	}
+}
+
+// assertOutput fails the test if the output of the runner does not contain
+// the string.
+func (r *e2eRun) assertOutput(t *testing.T, s string) {
+	t.Helper()
+	if !strings.Contains(r.output, s) {
+		t.Fatalf("expected output to contain %q\nOutput:\n%s", s, r.output)
+	}
+}
+
+// assertFinalRunning fails the test if the last row of the result does not
+// have the given number of running tasks.
+func (r *e2eRun) assertFinalRunning(t *testing.T, name, running string) {
+	t.Helper()
+	records := r.readCSV(t, name)
+	if len(records) < 2 || records[0][len(records[0])-1] != "running" {
+		t.Fatalf("unexpected contents of %s: %v", name, records)
+	}
+	last := records[len(records)-1]
+	if got := last[len(last)-1]; got != running {
+		t.Fatalf("expected %s running at the end of %s, got %s", running, name, got)
+	}
+}
+
+// assertTeardownLast fails the test unless teardown was invoked exactly
+// once, after every other step.
+func (r *e2eRun) assertTeardownLast(t *testing.T) {
+	t.Helper()
+	calls := r.calls(t)
+	var teardowns int
+	for _, call := range calls {
+		if call == "teardown" {
+			teardowns++
+		}
+	}
+	if teardowns != 1 || calls[len(calls)-1] != "teardown" {
+		t.Fatalf("expected a single teardown after every other step, got %v", calls)
+	}
+}
+
+func TestE2E_Success(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_LINES=5"})
+	run.assertCode(t, exitCodeOK)
+	run.assertFinalRunning(t, "result.csv", "5")
+	run.assertTeardownLast(t)
+
+	// The optional info sub-command is tried first. Status and run execute
+	// concurrently, so may be invoked in either order.
+	expected := []string{"info", "setup", "status", "run", "teardown"}
+	calls := run.calls(t)
+	if len(calls) == len(expected) && calls[2] == "run" {
+		calls[2], calls[3] = calls[3], calls[2]
+	}
+	if strings.Join(calls, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected steps %v, got %v", expected, calls)
+	}
+
+	// Every step is on the timeline
+	seen := make(map[string]int)
+	for _, record := range run.readCSV(t, "result.timeline.csv")[1:] {
+		seen[record[1]+" "+record[2]]++
+	}
+	for _, phase := range expected[1:] {
+		if seen[phase+" start"] != 1 || seen[phase+" end"] != 1 {
+			t.Fatalf("expected start and end of %q on the timeline, got %v", phase, seen)
+		}
+	}
+}
+
+func TestE2E_StepFailures(t *testing.T) {
+	cases := []struct {
+		phase string
+
+		// result is the result file which is written, if any.
+		result string
+	}{
+		{phase: "setup"},
+		{phase: "status", result: "result.incomplete.csv"},
+		{phase: "run", result: "result.incomplete.csv"},
+		{phase: "teardown", result: "result.csv"},
+	}
+	for _, c := range cases {
+		t.Run(c.phase, func(t *testing.T) {
+			run := runRunner(t, []string{"FAKE_FAIL=" + c.phase})
+			run.assertCode(t, exitCodeFailed)
+			run.assertTeardownLast(t)
+			run.assertOutput(t, fmt.Sprintf("[ERR] runner: step '%s' failed", c.phase))
+			if c.phase != "status" {
+				run.assertOutput(t, fmt.Sprintf("Stdout: fake %s failed", c.phase))
+			}
+
+			for _, name := range []string{"result.csv", "result.incomplete.csv"} {
+				if want := name == c.result; run.exists(name) != want {
+					t.Fatalf("expected %s to exist: %v", name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestE2E_MalformedStatus(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_LINES=4", "FAKE_MALFORMED=1"})
+	run.assertCode(t, exitCodeOK)
+	run.assertOutput(t, `invalid metric payload: "not a metric"`)
+	run.assertOutput(t, "failed parsing metric value")
+	run.assertFinalRunning(t, "result.csv", "4")
+}
+
+func TestE2E_HugeStatus(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_LINES=200000"})
+	run.assertCode(t, exitCodeOK)
+	run.assertFinalRunning(t, "result.csv", "200000")
+}
+
+func TestE2E_Labels(t *testing.T) {
+	env := []string{"FAKE_LINES=2", "FAKE_LABELS=a,b"}
+	cases := []struct {
+		name string
+		args []string
+
+		// header is the header expected of the result.
+		header []string
+	}{
+		{
+			name:   "series",
+			header: []string{"elapsed_ms", "running{class=a}", "running{class=b}"},
+		},
+		{
+			name:   "filter",
+			args:   []string{"-filter=class=b"},
+			header: []string{"elapsed_ms", "running{class=b}"},
+		},
+		{
+			name:   "total",
+			args:   []string{"-group-by="},
+			header: []string{"elapsed_ms", "running"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			run := runRunner(t, env, c.args...)
+			run.assertCode(t, exitCodeOK)
+			records := run.readCSV(t, "result.csv")
+			if got := strings.Join(records[0], " "); got != strings.Join(c.header, " ") {
+				t.Fatalf("expected header %v, got %v", c.header, records[0])
+			}
+		})
+	}
+
+	// The labeled series are summed to derive the total running
+	run := runRunner(t, env, "-group-by=")
+	run.assertFinalRunning(t, "result.csv", "4")
+}
+
+func TestE2E_Kinds(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_SAMPLES=10"}, "-iterations=2")
+	run.assertCode(t, exitCodeOK)
+	if records := run.readCSV(t, "result-1.samples.csv"); len(records) != 11 {
+		t.Fatalf("expected 10 samples, got %v", records)
+	}
+
+	// The deltas are summed, and percentiles derived from the samples
+	expected := map[string]string{
+		"final_started": "10",
+		"count_latency": "10",
+		"p50_latency":   "5",
+		"p99_latency":   "10",
+	}
+	for _, record := range run.readCSV(t, "aggregate.csv")[1:] {
+		if want, ok := expected[record[0]]; ok {
+			if record[2] != want {
+				t.Fatalf("expected mean %s of %s, got %s", want, record[0], record[2])
+			}
+			delete(expected, record[0])
+		}
+	}
+	if len(expected) != 0 {
+		t.Fatalf("expected aggregates of %v", expected)
+	}
+}
+
+func TestE2E_JSONStatus(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_JSON=1", "FAKE_LINES=4"}, "-output=out")
+	run.assertCode(t, exitCodeOK)
+	dirs, err := filepath.Glob(filepath.Join(run.dir, "out", "*"))
+	if err != nil || len(dirs) != 1 {
+		t.Fatalf("expected a single run directory, got %v: %v", dirs, err)
+	}
+	dir, err := filepath.Rel(run.dir, dirs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.assertFinalRunning(t, filepath.Join(dir, "result.csv"), "4")
+
+	// Metric names are not restricted in JSON records
+	records := run.readCSV(t, filepath.Join(dir, "result.csv"))
+	if strings.Join(records[0], " ") != "elapsed_ms a|b running" {
+		t.Fatalf("unexpected header %v", records[0])
+	}
+
+	events := run.readCSV(t, filepath.Join(dir, "result.events.csv"))
+	if len(events) != 2 || events[1][2] != "all jobs submitted" || events[1][3] != "class=a" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	manifest, err := ioutil.ReadFile(filepath.Join(dirs[0], "manifest.json"))
+	if err != nil || !strings.Contains(string(manifest), `"a|b": "tasks"`) {
+		t.Fatalf("expected the unit of a|b in the manifest: %v\n%s", err, manifest)
+	}
+}
+
+func TestE2E_Events(t *testing.T) {
+	// The fake is compared against itself, so that a report is written
+	env := []string{"FAKE_EVENTS=jobs submitted,node drained"}
+	run := runRunner(t, env, fakeBin)
+	run.assertCode(t, exitCodeOK)
+
+	results, err := filepath.Glob(filepath.Join(run.dir, "*-results", "result.events.csv"))
+	if err != nil || len(results) != 2 {
+		t.Fatalf("expected events of both implementations, got %v: %v", results, err)
+	}
+	name, err := filepath.Rel(run.dir, results[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := run.readCSV(t, name)
+	if len(events) != 3 || events[1][1] != "fake" || events[2][2] != "node drained" {
+		t.Fatalf("unexpected events %v", events)
+	}
+
+	// Each event is overlaid on the chart and listed in the report
+	report, err := ioutil.ReadFile(filepath.Join(run.dir, "comparison.html"))
+	if err != nil {
+		t.Fatalf("failed reading report: %v", err)
+	}
+	if n := strings.Count(string(report), "stroke-dasharray"); n != 4 {
+		t.Fatalf("expected 4 events on the chart, got %d", n)
+	}
+	if !strings.Contains(string(report), "<td>node drained</td>") {
+		t.Fatalf("expected the events to be listed in the report")
+	}
+
+	// The events of each result are also overlaid on its own chart
+	report, err = ioutil.ReadFile(filepath.Join(filepath.Dir(results[0]), "result.html"))
+	if err != nil {
+		t.Fatalf("failed reading report: %v", err)
+	}
+	if n := strings.Count(string(report), "stroke-dasharray"); n != 2 {
+		t.Fatalf("expected 2 events on the chart of the result, got %d", n)
+	}
+}
+
+func TestE2E_HTTP(t *testing.T) {
+	// serve serves an implementation over HTTP, recording each step in the
+	// calls log within dir. The step named fail responds with a 500 and a
+	// body resembling status output, which must not be treated as such.
+	serve := func(t *testing.T, dir, fail string) *httptest.Server {
+		var lock sync.Mutex
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			phase := strings.TrimPrefix(r.URL.Path, "/")
+			var req struct {
+				Env map[string]string `json:"env"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Env["JOBS"] != "5" {
+				http.Error(w, fmt.Sprintf("unexpected request %v: %v", req, err), http.StatusBadRequest)
+				return
+			}
+
+			lock.Lock()
+			fh, err := os.OpenFile(filepath.Join(dir, "calls.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err == nil {
+				fmt.Fprintln(fh, phase)
+				fh.Close()
+			}
+			lock.Unlock()
+
+			switch {
+			case phase == fail:
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(w, "running|99\nhttp %s failed\n", phase)
+			case phase == "status":
+				ts := time.Now().UnixNano()
+				for i := 1; i <= 5; i++ {
+					fmt.Fprintf(w, "running|%d|%d\n", i, ts)
+				}
+			case phase == "info":
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	t.Run("success", func(t *testing.T) {
+		dir := t.TempDir()
+		srv := serve(t, dir, "")
+		run := runRunnerIn(t, dir, nil, "-env", "JOBS=5", srv.URL)
+		run.assertCode(t, exitCodeOK)
+		run.assertFinalRunning(t, "result.csv", "5")
+		run.assertTeardownLast(t)
+		if calls := run.calls(t); len(calls) != 5 || calls[1] != "setup" {
+			t.Fatalf("unexpected steps %v", calls)
+		}
+	})
+
+	for _, phase := range []string{"run", "status"} {
+		t.Run(phase+" failure", func(t *testing.T) {
+			dir := t.TempDir()
+			srv := serve(t, dir, phase)
+			run := runRunnerIn(t, dir, nil, "-env", "JOBS=5", srv.URL)
+			run.assertCode(t, exitCodeFailed)
+			run.assertOutput(t, fmt.Sprintf("[ERR] runner: step '%s' failed", phase))
+
+			// The canceled status request may only reach the server after
+			// the teardown, so it is not ordered against it.
+			var calls []string
+			for _, call := range run.calls(t) {
+				if call != "status" {
+					calls = append(calls, call)
+				}
+			}
+			if len(calls) == 0 || calls[len(calls)-1] != "teardown" {
+				t.Fatalf("expected teardown after every other step, got %v", run.calls(t))
+			}
+
+			// The body of the failed response is shown as stderr, and is not
+			// treated as output.
+			run.assertOutput(t, fmt.Sprintf("http %s failed", phase))
+			if strings.Contains(run.output, "Stdout: running|99") {
+				t.Fatalf("expected the body to not be stdout\nOutput:\n%s", run.output)
+			}
+			for _, record := range run.readCSV(t, "result.incomplete.csv")[1:] {
+				if record[len(record)-1] == "99" {
+					t.Fatalf("expected the body to not be status, got %v", record)
+				}
+			}
+		})
+	}
+}
+
+func TestE2E_Plugin(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		// The plugin only reports the tasks started by setup if it is
+		// launched once and reused by every step.
+		run := runRunnerIn(t, t.TempDir(), nil, "-env", "FAKE_JOBS=4", "-output=out", "plugin:"+pluginBin)
+		run.assertCode(t, exitCodeOK)
+		dirs, err := filepath.Glob(filepath.Join(run.dir, "out", "*"))
+		if err != nil || len(dirs) != 1 {
+			t.Fatalf("expected a single run directory, got %v: %v", dirs, err)
+		}
+		dir, err := filepath.Rel(run.dir, dirs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		run.assertFinalRunning(t, filepath.Join(dir, "result.csv"), "4")
+		run.assertTeardownLast(t)
+		run.assertOutput(t, `implementation "plugin" version "1"`)
+		events := run.readCSV(t, filepath.Join(dir, "result.events.csv"))
+		if len(events) != 2 || events[1][2] != "tasks started" {
+			t.Fatalf("unexpected events %v", events)
+		}
+
+		// The labels and unit of a metric are carried over the protocol
+		records := run.readCSV(t, filepath.Join(dir, "result.csv"))
+		if strings.Join(records[0], " ") != "elapsed_ms placed{class=a} running" {
+			t.Fatalf("unexpected header %v", records[0])
+		}
+		manifest, err := ioutil.ReadFile(filepath.Join(dirs[0], "manifest.json"))
+		if err != nil || !strings.Contains(string(manifest), `"placed": "tasks"`) {
+			t.Fatalf("expected the unit of placed in the manifest: %v\n%s", err, manifest)
+		}
+	})
+
+	for _, phase := range []string{"setup", "run", "status", "teardown"} {
+		t.Run(phase+" failure", func(t *testing.T) {
+			env := []string{"FAKE_FAIL=" + phase}
+			run := runRunnerIn(t, t.TempDir(), env, "-env", "FAKE_JOBS=4", "plugin:"+pluginBin)
+			run.assertCode(t, exitCodeFailed)
+			run.assertTeardownLast(t)
+			run.assertOutput(t, fmt.Sprintf("[ERR] runner: step '%s' failed", phase))
+			run.assertOutput(t, fmt.Sprintf("plugin %s failed", phase))
+		})
+	}
+}
+
+func TestE2E_Suite(t *testing.T) {
+	// The suite sets a run timeout which the slow benchmark exceeds, and
+	// which the plain benchmark overrides. The swept benchmark overrides the
+	// environment of the suite so it does not sleep.
+	dir := t.TempDir()
+	suite := fmt.Sprintf(`{
+		"output": "out",
+		"env": {"FAKE_LINES": "2", "FAKE_SLEEP": "run=2s"},
+		"timeouts": {"run": "200ms"},
+		"benchmarks": [
+			{"name": "slow", "path": %[1]q},
+			{"name": "swept", "path": %[1]q, "env": {"FAKE_SLEEP": ""}, "sweep": {"FAKE_LINES": ["3", "4"]}},
+			{"name": "plain", "path": %[1]q, "env": {"FAKE_LINES": "5", "FAKE_SLEEP": "run=300ms"}, "timeouts": {"run": "1m"}}
+		]
+	}`, fakeBin)
+	if err := ioutil.WriteFile(filepath.Join(dir, "suite.json"), []byte(suite), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run := runRunnerIn(t, dir, nil, "-suite=suite.json")
+	run.assertCode(t, exitCodeTimeout)
+	run.assertOutput(t, `benchmark "slow" failed`)
+	if run.exists("out/slow/result.csv") || !run.exists("out/slow/result.incomplete.csv") {
+		t.Fatalf("expected the slow benchmark to time out")
+	}
+
+	run.assertFinalRunning(t, "out/swept/FAKE_LINES=3/result.csv", "3")
+	run.assertFinalRunning(t, "out/swept/FAKE_LINES=4/result.csv", "4")
+	records := run.readCSV(t, "out/swept/sweep.csv")
+	if len(records) != 3 || records[0][0] != "FAKE_LINES" || records[0][2] != "peak_running" ||
+		records[1][0] != "3" || records[1][2] != "3" || records[2][0] != "4" || records[2][2] != "4" {
+		t.Fatalf("unexpected sweep summary: %v", records)
+	}
+
+	run.assertFinalRunning(t, "out/plain/result.csv", "5")
+}
+
+func TestE2E_InvalidSuite(t *testing.T) {
+	cases := map[string]string{
+		"empty sweep":    `"sweep": {"JOBS": []}`,
+		"empty value":    `"sweep": {"JOBS": ["1", ""]}`,
+		"output outside": `"output": "../elsewhere"`,
+		"absolute":       `"output": "/tmp/elsewhere"`,
+		"separator":      `"output": "a/b"`,
+		"empty name":     `"sweep": {"": ["1"]}`,
+		"equals in name": `"sweep": {"A=B": ["1"]}`,
+		"space in name":  `"sweep": {"JOBS ": ["1"]}`,
+	}
+	for name, field := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			suite := fmt.Sprintf(`{"benchmarks": [{"name": "fake", "path": %q, %s}]}`, fakeBin, field)
+			if err := ioutil.WriteFile(filepath.Join(dir, "suite.json"), []byte(suite), 0644); err != nil {
+				t.Fatal(err)
+			}
+			run := runRunnerIn(t, dir, nil, "-suite=suite.json")
+			run.assertCode(t, exitCodeFailed)
+			run.assertOutput(t, `benchmark "fake" in suite`)
+			if calls := run.calls(t); len(calls) != 0 {
+				t.Fatalf("expected the fake not to be invoked, got %v", calls)
+			}
+		})
+	}
+}
+
+func TestE2E_Timeout(t *testing.T) {
+	start := time.Now()
+	run := runRunner(t, []string{"FAKE_SLEEP=run=1m"}, "-run-timeout=500ms")
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Fatalf("runner took %s to stop the step which timed out", elapsed)
+	}
+	run.assertCode(t, exitCodeTimeout)
+	run.assertOutput(t, "step 'run' timed out after 500ms")
+	run.assertTeardownLast(t)
+	if !run.exists("result.incomplete.csv") {
+		t.Fatalf("expected a partial result")
+	}
+}
+
+func TestE2E_Validate(t *testing.T) {
+	info := `FAKE_INFO={"name":"fake","version":"1","validate":true}`
+
+	t.Run("valid", func(t *testing.T) {
+		run := runRunner(t, []string{info})
+		run.assertCode(t, exitCodeOK)
+		run.assertTeardownLast(t)
+		out, err := ioutil.ReadFile(filepath.Join(run.dir, "result.validate.txt"))
+		if err != nil || string(out) != "fake validated\n" {
+			t.Fatalf("unexpected validation output %q: %v", out, err)
+		}
+		run.assertOutput(t, "fake validated")
+		if !run.exists("info.json") {
+			t.Fatalf("expected info.json to be written")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		run := runRunner(t, []string{info, "FAKE_INVALID=1"})
+		run.assertCode(t, exitCodeInvalid)
+		run.assertTeardownLast(t)
+		run.assertOutput(t, "[ERR] runner: benchmark is invalid")
+		if !run.exists("result.invalid.csv") || run.exists("result.csv") {
+			t.Fatalf("expected only result.invalid.csv to be written")
+		}
+	})
+}
+
+func TestE2E_RequiredEnv(t *testing.T) {
+	run := runRunner(t, []string{`FAKE_INFO={"name":"fake","required_env":["FAKE_MISSING"]}`})
+	run.assertCode(t, exitCodeFailed)
+	run.assertOutput(t, "requires environment variables to be set: FAKE_MISSING")
+	if calls := run.calls(t); len(calls) != 1 {
+		t.Fatalf("expected no steps after info, got %v", calls)
+	}
+}
+
+func TestE2E_Origin(t *testing.T) {
+	// The first update is reported well after both steps started, so the
+	// clock must be started once with 0 running at the origin.
+	for _, step := range []string{"run", "status"} {
+		t.Run(step, func(t *testing.T) {
+			run := runRunner(t, []string{"FAKE_SLEEP=status=300ms"}, "-origin="+step)
+			run.assertCode(t, exitCodeOK)
+
+			var starts int
+			for _, record := range run.readCSV(t, "result.timeline.csv")[1:] {
+				if record[2] != "start" || (record[1] != "run" && record[1] != "status") {
+					continue
+				}
+				starts++
+				elapsed, err := strconv.ParseInt(record[0], 10, 64)
+				switch {
+				case err != nil:
+					t.Fatalf("invalid elapsed time %q: %v", record[0], err)
+				case record[1] == step && elapsed != 0:
+					t.Fatalf("expected step %q to start at the origin, got %v", step, record)
+				case record[1] == "status" && elapsed > 0, record[1] == "run" && elapsed < 0:
+					t.Fatalf("expected step 'status' to start before step 'run', got %v", record)
+				}
+			}
+			if starts != 2 {
+				t.Fatalf("expected the start of both steps in the timeline")
+			}
+
+			records := run.readCSV(t, "result.csv")
+			if len(records) != 3 || records[1][0] != "0" || records[1][1] != "0" || records[2][1] != "3" {
+				t.Fatalf("expected 0 running at the origin followed by the update, got %v", records)
+			}
+			if elapsed, _ := strconv.Atoi(records[2][0]); elapsed < 250 {
+				t.Fatalf("expected the update to be reported after the origin, got %v", records)
+			}
+		})
+	}
+}
+
+func TestE2E_Iterations(t *testing.T) {
+	run := runRunner(t, []string{"FAKE_FAIL=run"}, "-iterations=3")
+	run.assertCode(t, exitCodeFailed)
+
+	// Execution stops at the first failed iteration
+	if !run.exists("result-1.incomplete.csv") || run.exists("result-2.csv") {
+		t.Fatalf("expected only the first iteration to execute")
+	}
+	run.assertTeardownLast(t)
+}
+
+func TestE2E_Aggregate(t *testing.T) {
+	// Each status invocation reports a different number of tasks. The
+	// warm-up reports 100, which must not be aggregated, and only the last
+	// iteration emits samples, which are then aggregated over one result.
+	env := []string{"FAKE_LINES=100,2,4,6", "FAKE_SAMPLES=0,0,0,2"}
+	run := runRunner(t, env, "-warmup=1", "-iterations=3")
+	run.assertCode(t, exitCodeOK)
+	if run.exists("result-4.csv") || !run.exists("result-3.csv") {
+		t.Fatalf("expected only the 3 measured iterations to be written")
+	}
+
+	rows := make(map[string]string)
+	for _, record := range run.readCSV(t, "aggregate.csv")[1:] {
+		rows[record[0]] = strings.Join(record[1:], ",")
+	}
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	margin := 4.303 * 2 / math.Sqrt(3)
+	spread := "3,4,2,2,6," + format(4-margin) + "," + format(4+margin)
+	expected := map[string]string{
+		"peak_running":  spread,
+		"final_running": spread,
+		"count_latency": "1,2,0,2,2,2,2",
+		"max_latency":   "1,2,0,2,2,2,2",
+	}
+	for metric, want := range expected {
+		if got := rows[metric]; got != want {
+			t.Fatalf("expected %s to aggregate to %s, got %q", metric, want, got)
+		}
+	}
+}
+
+func TestE2E_Check(t *testing.T) {
+	info := `FAKE_INFO={"name":"fake","version":"1","metrics":["running"],"expected_tasks":3,"validate":true}`
+	check := func(t *testing.T, env ...string) *e2eRun {
+		t.Helper()
+		return runRunnerIn(t, t.TempDir(), env, "check", "-timeout=30s", fakeBin)
+	}
+
+	t.Run("passing", func(t *testing.T) {
+		run := check(t, info)
+		run.assertCode(t, exitCodeOK)
+		run.assertTeardownLast(t)
+		for _, step := range []string{"unknown", "info", "setup", "run", "status", "validate", "teardown"} {
+			run.assertOutput(t, fmt.Sprintf("PASS  %-8s", step))
+		}
+		run.assertOutput(t, "reached the 3 tasks expected")
+		run.assertOutput(t, "0 failed")
+		if strings.Contains(run.output, "FAIL") {
+			t.Fatalf("expected every check to pass\nOutput:\n%s", run.output)
+		}
+	})
+
+	cases := []struct {
+		name string
+		env  []string
+
+		// step is the step whose check fails, and message part of the
+		// failure reported.
+		step    string
+		message string
+	}{
+		{"info", []string{"FAKE_INFO=not json"}, "info", "not a valid JSON object"},
+		{"metrics", []string{`FAKE_INFO={"name":"fake","version":"1","metrics":["other"]}`}, "info", "reserved 'running' metric"},
+		{"setup", []string{info, "FAKE_FAIL=setup"}, "setup", "fake setup failed"},
+		{"run", []string{info, "FAKE_FAIL=run"}, "run", "fake run failed"},
+		{"status", []string{info, "FAKE_FAIL=status"}, "status", "step 'status' failed"},
+		{"malformed", []string{info, "FAKE_MALFORMED=1"}, "status", `"not a metric"`},
+		{"empty", []string{info, "FAKE_LINES=0"}, "status", "no status output"},
+		{"expected", []string{info, "FAKE_LINES=2"}, "status", "'running' ended at 2, but 3 tasks were expected"},
+		{"validate", []string{info, "FAKE_INVALID=1"}, "validate", "fake validated"},
+		{"teardown", []string{info, "FAKE_FAIL=teardown"}, "teardown", "fake teardown failed"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			run := check(t, c.env...)
+			run.assertCode(t, exitCodeFailed)
+			run.assertOutput(t, fmt.Sprintf("FAIL  %-8s", c.step))
+			run.assertOutput(t, c.message)
+			run.assertTeardownLast(t)
+		})
+	}
+}
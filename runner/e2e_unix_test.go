@@ -0,0 +1,152 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+func TestE2E_Interrupt(t *testing.T) {
+	run := runInterrupted(t, []string{"FAKE_SLEEP=run=1m"}, 1, fakeBin)
+	run.assertCode(t, exitCodeSignal+int(syscall.SIGINT))
+	run.assertOutput(t, "[ERR] runner: benchmark interrupted by signal: interrupt")
+	run.assertTeardownLast(t)
+	if !run.exists("result.incomplete.csv") {
+		t.Fatalf("expected a partial result")
+	}
+}
+
+func TestE2E_InterruptPlugin(t *testing.T) {
+	// The plugin ignores the cancellation of its steps, so the teardown it
+	// hangs in is only stopped by the second interrupt killing the plugin.
+	start := time.Now()
+	run := runInterrupted(t, []string{"FAKE_HANG=run,teardown"}, 2, "plugin:"+pluginBin)
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Fatalf("runner took %s to stop the plugin", elapsed)
+	}
+	run.assertCode(t, exitCodeSignal+int(syscall.SIGINT))
+	run.assertTeardownLast(t)
+}
+
+// runInterrupted invokes the runner with the args, with the fake scripted by
+// the environment variables in env, and sends it SIGINT the given number of
+// times once the run step has started.
+func runInterrupted(t *testing.T, env []string, signals int, args ...string) *e2eRun {
+	t.Helper()
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "calls.log")
+	cmd := exec.Command(runnerBin, args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "FAKE_LOG="+logPath), env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed starting runner: %v", err)
+	}
+
+	// Interrupt the runner once the run step has started
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		calls, _ := ioutil.ReadFile(logPath)
+		if strings.Contains(string(calls), "run") {
+			break
+		}
+		if time.Now().After(deadline) {
+			cmd.Process.Kill()
+			t.Fatalf("run step was never started\nOutput:\n%s", out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for i := 0; i < signals; i++ {
+		if i > 0 {
+			time.Sleep(500 * time.Millisecond)
+		}
+		cmd.Process.Signal(syscall.SIGINT)
+	}
+
+	err := cmd.Wait()
+	run := &e2eRun{dir: dir, output: out.String()}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		run.code = exitErr.ExitCode()
+	}
+	return run
+}
+
+func TestE2E_StatusChannel(t *testing.T) {
+	// Stray lines on stdout are discarded once status is received on the
+	// status channel, even if they were read first
+	run := runRunner(t, []string{"FAKE_CHANNEL=1", "FAKE_LINES=4"}, "-output=out")
+	run.assertCode(t, exitCodeOK)
+	dirs, err := filepath.Glob(filepath.Join(run.dir, "out", "*"))
+	if err != nil || len(dirs) != 1 {
+		t.Fatalf("expected a single run directory, got %v: %v", dirs, err)
+	}
+	dir, err := filepath.Rel(run.dir, dirs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	run.assertFinalRunning(t, filepath.Join(dir, "result.csv"), "4")
+
+	// The stray lines are still captured
+	out, err := ioutil.ReadFile(filepath.Join(dirs[0], "logs", "result", "status.stdout.log"))
+	if err != nil || !strings.Contains(string(out), "stray output") {
+		t.Fatalf("expected stdout to be captured: %v\n%s", err, out)
+	}
+}
+
+func TestE2E_StatusStdout(t *testing.T) {
+	// Status output on stdout by an implementation which does not use the
+	// status channel is received as it is output, not once status exits
+	var lock sync.Mutex
+	var received []time.Time
+	r := bench.New(&bench.Config{
+		Path:   fakeBin,
+		Env:    []string{"FAKE_LINGER=3s"},
+		Stderr: ioutil.Discard,
+		Stdout: ioutil.Discard,
+		OnUpdate: func(*bench.Update) {
+			lock.Lock()
+			received = append(received, time.Now())
+			lock.Unlock()
+		},
+	})
+	res := r.Run(context.Background())
+	if res.Err != nil {
+		t.Fatalf("benchmark failed: %v", res.Err)
+	}
+
+	var status *bench.Step
+	for _, step := range res.Steps {
+		if step.Phase == "status" {
+			status = step
+		}
+	}
+	if status == nil {
+		t.Fatalf("expected the status step in %+v", res.Steps)
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("expected 3 updates, got %d", len(received))
+	}
+	for _, at := range received {
+		if !at.Before(status.End.Add(-time.Second)) {
+			t.Fatalf("expected updates to be received before status exited at %v, got one at %v",
+				status.End, at)
+		}
+	}
+}
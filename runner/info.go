@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// validateInfo checks that the benchmark described by the metadata of its
+// implementation can run with the given configuration.
+func validateInfo(info *bench.Info, conf *config) error {
+	env := conf.benchConfig(nil).Environ()
+	var missing []string
+	for _, name := range info.RequiredEnv {
+		if env[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("implementation %q requires environment variables to be set: %s",
+			info.Name, strings.Join(missing, ", "))
+	}
+
+	if len(info.Metrics) != 0 && !containsString(info.Metrics, "running") {
+		log.Printf("[WARN] runner: implementation %q does not emit the 'running' metric", info.Name)
+	}
+	return nil
+}
+
+// writeInfo stamps the results in the directory with the metadata of the
+// implementation, writing it to info.json.
+func writeInfo(info *bench.Info, dir string) error {
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, "info.json")
+	if err := ioutil.WriteFile(path, append(out, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing implementation info: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// config holds the options controlling how a benchmark is executed.
+type config struct {
+	path     string
+	timeouts bench.Timeouts
+
+	// env holds additional environment variables, in the form "key=value",
+	// passed to each sub-command of the test implementation.
+	env []string
+
+	// dir is the directory results are written to.
+	dir string
+
+	// iterations is the number of times the benchmark is measured, and
+	// warmup is the number of additional executions which precede them and
+	// whose results are discarded.
+	iterations int
+	warmup     int
+
+	// cooldown is how long to pause between consecutive executions of the
+	// benchmark, allowing the scheduler to settle.
+	cooldown time.Duration
+
+	// origin is the step from which elapsed time is measured, either
+	// bench.OriginRun or bench.OriginStatus. Defaults to bench.OriginRun.
+	origin string
+
+	// validate is whether the validate step is executed once the status
+	// step completes. It is enabled by the implementation's info.
+	validate bool
+
+	// filter holds the values of labels which every series of the results
+	// must have, and groupBy the labels the series are regrouped by, summing
+	// the series which share their values. If groupBy is nil, series are
+	// written as they were reported, and if it is empty, only the total of
+	// each metric is written.
+	filter  map[string]string
+	groupBy []string
+
+	// logs is whether the stdout and stderr of each step are captured to
+	// files within the output directory.
+	logs bool
+
+	// manifest records every benchmark executed, and record is the entry
+	// the executions of this benchmark are recorded in. Both are nil unless
+	// an output directory was given.
+	manifest *manifest
+	record   *manifestBenchmark
+}
+
+// benchConfig returns the configuration of the benchmark for the bench
+// package, whose steps are tracked by the supervisor if not nil.
+func (c *config) benchConfig(sup *supervisor) *bench.Config {
+	conf := &bench.Config{
+		Path:     c.path,
+		Timeouts: c.timeouts,
+		Env:      c.env,
+		Origin:   c.origin,
+		Validate: c.validate,
+		Stderr:   stepOutput,
+		Stdout:   stepOutput,
+	}
+	if sup != nil {
+		conf.Supervisor = sup.Supervisor
+	}
+	return conf
+}
+
+// series returns the series of the metrics which are written to the
+// results, filtered and grouped by their labels as configured.
+func (c *config) series(metrics bench.Metrics) bench.Metrics {
+	metrics = metrics.Filter(c.filter)
+	if c.groupBy != nil {
+		metrics = metrics.Group(c.groupBy)
+	}
+	return metrics
+}
+
+// sampleSeries returns the series of the samples which are written to the
+// results, filtered and grouped by their labels as configured.
+func (c *config) sampleSeries(samples bench.Samples) bench.Samples {
+	samples = samples.Filter(c.filter)
+	if c.groupBy != nil {
+		samples = samples.Group(c.groupBy)
+	}
+	return samples
+}
+
+// runIterations executes the benchmark the configured number of times,
+// after first executing any warm-up iterations. If the implementation
+// describes itself using the info sub-command, the configuration is first
+// validated against its requirements. With more than one iteration, the
+// result of each is written to its own numbered file and the derived
+// metrics of every iteration are aggregated. Execution stops at the first
+// failed iteration. Returns the exit code of the runner, along with the
+// result of each measured iteration which completed.
+func runIterations(conf *config, sup *supervisor) (int, []*result) {
+	// Describe the implementation, if it supports it, and make sure it can
+	// run with the given configuration.
+	info, err := bench.New(conf.benchConfig(sup)).Info(context.Background())
+	if err == nil && info != nil {
+		log.Printf("[INFO] runner: implementation %q version %q", info.Name, info.Version)
+		if err = validateInfo(info, conf); err == nil {
+			err = writeInfo(info, conf.dir)
+		}
+	}
+	if err != nil {
+		if sig := sup.Interrupted(); sig != nil {
+			return signalExitCode(sig), nil
+		}
+		log.Printf("[ERR] runner: %v", err)
+		return exitCodeFailed, nil
+	}
+	var expected int
+	c := *conf
+	if info != nil {
+		expected = info.ExpectedTasks
+		c.validate = info.Validate
+	}
+	c.record = conf.manifest.addBenchmark(conf, info)
+	conf = &c
+
+	var results []*result
+	var summaries []map[string]float64
+	code := exitCodeOK
+	for i := 0; i < conf.warmup+conf.iterations; i++ {
+		if i > 0 {
+			if err := sup.Sleep(conf.cooldown); err != nil {
+				code = signalExitCode(sup.Interrupted())
+				break
+			}
+		}
+
+		var name string
+		switch n := i - conf.warmup + 1; {
+		case n <= 0:
+			log.Printf("[INFO] runner: starting warm-up iteration %d of %d", i+1, conf.warmup)
+		case conf.iterations == 1:
+			name = "result"
+		default:
+			log.Printf("[INFO] runner: starting iteration %d of %d", n, conf.iterations)
+			name = fmt.Sprintf("result-%d", n)
+		}
+
+		res := runBenchmark(conf, sup, name)
+		if code = res.exitCode(); code != exitCodeOK {
+			break
+		}
+		if name != "" {
+			res.summary = summarize(res.totals, expected)
+			summarizeSamples(res.summary, res.samples)
+			for name, value := range res.Durations {
+				res.summary[name] = value
+			}
+			if peak := res.summary["peak_running"]; expected > 0 && peak < float64(expected) {
+				log.Printf("[WARN] runner: only %v of %d expected tasks were running", peak, expected)
+			}
+			path := filepath.Join(conf.dir, name+".html")
+			if err := writeResultReport(path, name, res); err != nil {
+				log.Printf("[ERR] runner: failed writing report: %v", err)
+			} else {
+				log.Printf("[INFO] runner: report written to %s", path)
+			}
+			results = append(results, res)
+			summaries = append(summaries, res.summary)
+		}
+	}
+
+	// Aggregate whatever iterations completed. If some did not, the
+	// aggregate is marked as incomplete in the same way as the results.
+	if conf.iterations > 1 && len(summaries) > 0 {
+		path := filepath.Join(conf.dir, "aggregate.csv")
+		if code != exitCodeOK {
+			path = filepath.Join(conf.dir, "aggregate.incomplete.csv")
+		}
+		if err := writeAggregates(path, aggregateSummaries(summaries)); err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			if code == exitCodeOK {
+				code = exitCodeFailed
+			}
+		}
+	}
+	return code, results
+}
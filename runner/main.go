@@ -1,72 +1,296 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
 )
 
+// stepOutput receives the stderr of every sub-command, along with the stdout
+// of every step other than status, which is piped through to the terminal.
+var stepOutput io.Writer = os.Stdout
+
+// runnerVersion is the version of the runner, recorded in the manifest.
+const runnerVersion = "0.1.0"
+
 func main() {
+	os.Exit(realMain(os.Args[1:]))
+}
+
+func realMain(args []string) int {
+	defer bench.ClosePlugins()
+	if len(args) > 0 && args[0] == "check" {
+		return runCheck(args[1:])
+	}
+
+	// Parse the flags
+	conf := &config{dir: "."}
+	var sweep sweepFlag
+	var suitePath, output string
+	flags := flag.NewFlagSet("bench-runner", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.DurationVar(&conf.timeouts.Setup, "setup-timeout", 0, "")
+	flags.DurationVar(&conf.timeouts.Run, "run-timeout", 0, "")
+	flags.DurationVar(&conf.timeouts.Status, "status-timeout", 0, "")
+	flags.DurationVar(&conf.timeouts.Validate, "validate-timeout", 0, "")
+	flags.DurationVar(&conf.timeouts.Teardown, "teardown-timeout", 0, "")
+	flags.IntVar(&conf.iterations, "iterations", 1, "")
+	flags.IntVar(&conf.warmup, "warmup", 0, "")
+	flags.DurationVar(&conf.cooldown, "cooldown", 0, "")
+	flags.Var((*envFlag)(&conf.env), "env", "")
+	flags.Var(&sweep, "sweep", "")
+	flags.Var((*filterFlag)(&conf.filter), "filter", "")
+	flags.Var((*groupByFlag)(&conf.groupBy), "group-by", "")
+	flags.StringVar(&conf.origin, "origin", bench.OriginRun, "")
+	flags.StringVar(&suitePath, "suite", "", "")
+	flags.StringVar(&output, "output", "", "")
+	if err := flags.Parse(args); err != nil {
+		return exitCodeFailed
+	}
+
 	// Check the args
-	if len(os.Args) != 2 {
-		log.Fatalln("usage: bench-runner <path>")
+	paths := flags.Args()
+	var s *suite
+	if suitePath != "" {
+		if len(paths) != 0 {
+			flags.Usage()
+			return exitCodeFailed
+		}
+		var err error
+		if s, err = loadSuite(suitePath); err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			return exitCodeFailed
+		}
+	} else {
+		if conf.origin != bench.OriginRun && conf.origin != bench.OriginStatus {
+			log.Printf("[ERR] runner: origin must be %q or %q", bench.OriginRun, bench.OriginStatus)
+			return exitCodeFailed
+		}
+		if len(paths) == 0 || conf.iterations < 1 || conf.warmup < 0 {
+			flags.Usage()
+			return exitCodeFailed
+		}
+		if len(paths) > 1 && len(sweep) != 0 {
+			log.Printf("[ERR] runner: sweeps cannot be combined with comparisons")
+			return exitCodeFailed
+		}
+		for _, path := range paths {
+			if err := checkExecutable(path); err != nil {
+				log.Printf("[ERR] runner: %v", err)
+				return exitCodeFailed
+			}
+		}
+		conf.path = paths[0]
 	}
-	path := os.Args[1]
 
-	// Make sure the script exists and is executable
-	fi, err := os.Stat(path)
-	if err != nil {
-		log.Fatalf("[ERR] runner: failed to stat %q: %v", path, err)
+	// Write everything to a new directory for this run, if requested,
+	// along with a manifest and a copy of the log.
+	if output != "" {
+		start := time.Now()
+		dir, err := createRunDir(output, start)
+		if err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			return exitCodeFailed
+		}
+		logFile, err := os.Create(filepath.Join(dir, "runner.log"))
+		if err != nil {
+			log.Printf("[ERR] runner: failed creating log file: %v", err)
+			return exitCodeFailed
+		}
+		defer logFile.Close()
+		log.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		defer log.SetOutput(os.Stderr)
+		stepOutput = io.MultiWriter(os.Stdout, logFile)
+		log.Printf("[INFO] runner: writing results to %s", dir)
+
+		conf.dir = dir
+		conf.logs = true
+		conf.manifest = newManifest(dir, args, start)
+		if s != nil {
+			s.Output = filepath.Join(dir, s.Output)
+			s.logs = true
+			s.manifest = conf.manifest
+		}
 	}
-	if fi.Mode().Perm()|0111 == 0 {
-		log.Fatalf("[ERR] runner: file %q is not executable", path)
+
+	sup := newSupervisor()
+	defer sup.stop()
+	code := run(conf, s, paths, sweep, sup)
+	if err := conf.manifest.finish(code); err != nil {
+		log.Printf("[ERR] runner: %v", err)
 	}
+	return code
+}
 
-	// Perform setup
-	log.Println("[DEBUG] runner: executing step 'setup'")
-	setupCmd := exec.Command(path, "setup")
-	setupCmd.Stderr = os.Stdout
-	if out, err := setupCmd.Output(); err != nil {
-		log.Fatalf("[ERR] runner: failed running setup: %v\nStdout: %s", err, string(out))
+// run executes the suite, if given, or otherwise the benchmark of each
+// path. Returns the exit code of the runner.
+func run(conf *config, s *suite, paths []string, sweep []*sweepParam, sup *supervisor) int {
+	switch {
+	case s != nil:
+		return runSuite(s, sup)
+	case len(paths) > 1:
+		return runCompare(conf, paths, sup)
+	case len(sweep) != 0:
+		return runSweep(conf, sweep, sup)
 	}
+	code, _ := runIterations(conf, sup)
+	return code
+}
 
-	// Always run the teardown
-	defer func() {
-		log.Println("[DEBUG] runner: executing step 'teardown'")
-		teardownCmd := exec.Command(path, "teardown")
-		teardownCmd.Stderr = os.Stdout
-		if out, err := teardownCmd.Output(); err != nil {
-			log.Fatalf("[ERR] runner: failed teardown: %v\nStdout: %s", err, string(out))
-		}
-	}()
+// envFlag implements flag.Value, collecting each -env flag given as an
+// environment variable in the form "key=value".
+type envFlag []string
 
-	// Start running the status collector
-	log.Println("[DEBUG] runner: executing step 'status'")
-	statusCmd := exec.Command(path, "status")
-	statusCmd.Stderr = os.Stdout
-	outBuf, err := statusCmd.StdoutPipe()
-	if err != nil {
-		log.Fatalf("[ERR] runner: failed attaching stdout: %v", err)
+func (f *envFlag) String() string {
+	return ""
+}
+
+// Set parses an environment variable of the form KEY=value.
+func (f *envFlag) Set(value string) error {
+	if strings.Index(value, "=") < 1 {
+		return fmt.Errorf("env must be of the form KEY=value")
 	}
-	if err := statusCmd.Start(); err != nil {
-		log.Fatalf("[ERR] runner: failed to run status submitter: %v", err)
+	*f = append(*f, value)
+	return nil
+}
+
+// filterFlag implements flag.Value, collecting each -filter flag given as
+// the value a label must have, in the form "label=value".
+type filterFlag map[string]string
+
+func (f *filterFlag) String() string {
+	return ""
+}
+
+// Set parses a label filter of the form label=value.
+func (f *filterFlag) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("filter must be of the form label=value")
+	}
+	if *f == nil {
+		*f = make(map[string]string)
 	}
+	(*f)[kv[0]] = kv[1]
+	return nil
+}
 
-	// Start listening for updates
-	srv := newStatusServer(outBuf)
-	go srv.run()
+// groupByFlag implements flag.Value, parsing the labels given to -group-by
+// as a comma separated list. An empty list groups by no labels.
+type groupByFlag []string
 
-	// Start running the benchmark
-	log.Println("[DEBUG] runner: executing step 'run'")
-	runCmd := exec.Command(path, "run")
-	runCmd.Stderr = os.Stdout
-	if out, err := runCmd.Output(); err != nil {
-		log.Fatalf("[ERR] runner: failed running benchmark: %v\nStdout: %s", err, string(out))
+func (f *groupByFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set parses a list of labels of the form l1,l2.
+func (f *groupByFlag) Set(value string) error {
+	*f = []string{}
+	for _, label := range strings.Split(value, ",") {
+		if label = strings.TrimSpace(label); label != "" {
+			*f = append(*f, label)
+		}
 	}
+	return nil
+}
 
-	// Wait for the status command to return
-	log.Println("[DEBUG] runner: waiting for step 'status' to complete...")
-	if err := statusCmd.Wait(); err != nil {
-		log.Fatalf("[ERR] runner: status command got error: %v", err)
+// checkExecutable makes sure the test implementation exists and is
+// executable. Implementations served over HTTP are not checked.
+func checkExecutable(path string) error {
+	if bench.IsRemote(path) {
+		return nil
+	}
+	path = strings.TrimPrefix(path, bench.PluginPrefix)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %v", path, err)
 	}
+	if fi.Mode().Perm()&0111 == 0 {
+		return fmt.Errorf("file %q is not executable", path)
+	}
+	return nil
 }
+
+const usage = `
+Usage: bench-runner [options] <path> [<path>...]
+       bench-runner -suite=<file>
+       bench-runner check [-timeout=<duration>] [-env=<KEY=value>] <path>
+
+  Runs the benchmark implemented by the executable at <path>. The executable
+  is invoked with each of the setup, run, status and teardown sub-commands,
+  along with the optional info and validate sub-commands.
+
+  The path may also be the http:// or https:// URL of an implementation served
+  over HTTP, in which case each sub-command is invoked by a POST request to
+  the path named after it, such as /setup. Implementations served over HTTP
+  do not inherit the environment of the runner; use -env instead.
+
+  A path of the form plugin:<path> refers to a Go plugin built with the
+  github.com/hashicorp/schedbench/plugin package. The plugin is launched once
+  and each step is invoked as an RPC, rather than as a sub-command.
+
+  If multiple paths are given, each implementation is run in sequence and a
+  comparison of their results is written.
+
+  Alternatively, runs every benchmark described by a JSON suite file. All
+  options other than -output are then taken from the suite file.
+
+Options:
+
+  -iterations=<n>               Number of times to measure the benchmark.
+                                Defaults to 1.
+  -warmup=<n>                   Number of additional iterations to execute
+                                first, whose results are discarded.
+  -cooldown=<duration>          Time to pause between consecutive executions
+                                of the benchmark.
+  -sweep=<KEY=v1,v2,...>        Executes the benchmark once for each value of
+                                the environment variable KEY. May be given
+                                multiple times to execute every combination.
+  -env=<KEY=value>              Sets an environment variable for every
+                                sub-command. May be given multiple times.
+  -origin=<run|status>          Step from whose start elapsed time is
+                                measured. Defaults to run.
+  -filter=<label=value>         Only writes the series of metrics with the
+                                label set to the value. May be given
+                                multiple times.
+  -group-by=<l1,l2,...>         Writes each metric grouped by only the given
+                                labels, summing the series within a group.
+                                If empty, only the total of each metric is
+                                written. By default, every series is written
+                                as reported.
+  -output=<dir>                 Writes results to a new directory within dir,
+                                named after the time the run started, along
+                                with a manifest.json describing the run, a
+                                copy of the log, and the output of each step
+                                under logs/. By default, results are written
+                                to the working directory.
+
+  -setup-timeout=<duration>     Maximum time the setup step may run for.
+  -run-timeout=<duration>       Maximum time the run step may run for.
+  -status-timeout=<duration>    Maximum time the status step may run for.
+  -validate-timeout=<duration>  Maximum time the validate step may run for.
+  -teardown-timeout=<duration>  Maximum time the teardown step may run for.
+
+  Timeouts are given as Go durations, such as "90s" or "10m". A step which
+  exceeds its timeout is sent SIGTERM, followed by SIGKILL if it has not
+  exited within 10 seconds. Teardown is still executed after a timeout.
+  By default, steps may run indefinitely.
+
+  The check command exercises each sub-command of the implementation once,
+  strictly validating its exit codes and status output, and that 'running'
+  ends at the expected_tasks given by info, and prints a conformance report. Each step may run for the -timeout, which defaults to
+  10 minutes. The exit code is 1 if any check failed.
+
+  Teardown is always executed once the benchmark has started, including when
+  a step fails or the runner receives SIGINT or SIGTERM. Signals are forwarded
+  to the running sub-commands; a second signal kills them. The exit code is
+  1 if a step failed, 2 if the benchmark failed validation, 124 if a step
+  timed out, or 128 plus the signal number if the runner was interrupted.
+`
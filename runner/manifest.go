@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// runDirFormat is the layout of the name of the timestamped directory
+// created for each invocation of the runner within the output directory.
+const runDirFormat = "20060102-150405"
+
+// manifest describes an invocation of the runner, so that the results it
+// produced can be traced back to how they were obtained. It is written to
+// manifest.json in the run directory, and rewritten as the run progresses.
+type manifest struct {
+	RunnerVersion string       `json:"runner_version"`
+	Args          []string     `json:"args"`
+	Host          manifestHost `json:"host"`
+	StartTime     time.Time    `json:"start_time"`
+	EndTime       *time.Time   `json:"end_time,omitempty"`
+	ExitCode      *int         `json:"exit_code,omitempty"`
+
+	Benchmarks []*manifestBenchmark `json:"benchmarks"`
+
+	path string
+	lock sync.Mutex
+}
+
+// manifestHost describes the host the runner executed on.
+type manifestHost struct {
+	Hostname  string `json:"hostname"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	NumCPU    int    `json:"num_cpu"`
+	GoVersion string `json:"go_version"`
+}
+
+// manifestBenchmark records a test implementation and every execution of it.
+type manifestBenchmark struct {
+	Path string            `json:"path"`
+	Dir  string            `json:"dir"`
+	Env  map[string]string `json:"env,omitempty"`
+	Info *bench.Info       `json:"info,omitempty"`
+
+	Executions []*manifestExecution `json:"executions"`
+
+	m *manifest
+}
+
+// manifestExecution records a single execution of a benchmark. Result is the
+// base name of its result files, and is empty for warm-up iterations.
+type manifestExecution struct {
+	Result    string          `json:"result,omitempty"`
+	StartTime time.Time       `json:"start_time"`
+	Steps     []*manifestStep `json:"steps"`
+
+	// Units holds the unit of each metric for which the implementation
+	// gave one, and Events the events it emitted, in time order.
+	Units  map[string]string `json:"units,omitempty"`
+	Events []*bench.Event    `json:"events,omitempty"`
+}
+
+// manifestStep records how a step of an execution exited. ExitCode is -1 if
+// the step was killed by a signal or its connection was lost.
+type manifestStep struct {
+	Phase      string    `json:"phase"`
+	StartTime  time.Time `json:"start_time"`
+	DurationMs float64   `json:"duration_ms"`
+	ExitCode   int       `json:"exit_code"`
+	TimedOut   bool      `json:"timed_out,omitempty"`
+}
+
+// createRunDir creates a new directory within the output directory, named
+// after the current time, for the results of this invocation of the runner.
+func createRunDir(output string, now time.Time) (string, error) {
+	if err := os.MkdirAll(output, 0755); err != nil {
+		return "", fmt.Errorf("failed creating output directory: %v", err)
+	}
+	dir := filepath.Join(output, now.UTC().Format(runDirFormat))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed creating run directory: %v", err)
+	}
+	return dir, nil
+}
+
+// newManifest creates the manifest of an invocation of the runner with the
+// given arguments, which is written to the directory.
+func newManifest(dir string, args []string, start time.Time) *manifest {
+	hostname, _ := os.Hostname()
+	return &manifest{
+		RunnerVersion: runnerVersion,
+		Args:          args,
+		Host: manifestHost{
+			Hostname:  hostname,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			NumCPU:    runtime.NumCPU(),
+			GoVersion: runtime.Version(),
+		},
+		StartTime:  start,
+		Benchmarks: []*manifestBenchmark{},
+		path:       filepath.Join(dir, "manifest.json"),
+	}
+}
+
+// addBenchmark records a test implementation about to be executed with the
+// configuration. The environment holds the additional variables of the
+// configuration, along with the variables the implementation requires. It is
+// safe to call on a nil manifest, which records nothing.
+func (m *manifest) addBenchmark(conf *config, info *bench.Info) *manifestBenchmark {
+	if m == nil {
+		return nil
+	}
+	b := &manifestBenchmark{
+		Path:       conf.path,
+		Dir:        conf.dir,
+		Env:        make(map[string]string),
+		Info:       info,
+		Executions: []*manifestExecution{},
+		m:          m,
+	}
+	if info != nil {
+		env := conf.benchConfig(nil).Environ()
+		for _, name := range info.RequiredEnv {
+			if v, ok := env[name]; ok {
+				b.Env[name] = v
+			}
+		}
+	}
+	for _, kv := range conf.env {
+		if idx := strings.Index(kv, "="); idx > 0 {
+			b.Env[kv[:idx]] = kv[idx+1:]
+		}
+	}
+
+	m.lock.Lock()
+	m.Benchmarks = append(m.Benchmarks, b)
+	m.lock.Unlock()
+	return b
+}
+
+// addExecution records how each step of an execution of the benchmark
+// exited, along with the units of its metrics and its events. It is safe to
+// call on a nil benchmark, which records nothing.
+func (b *manifestBenchmark) addExecution(resultName string, res *bench.Result) error {
+	if b == nil {
+		return nil
+	}
+	e := &manifestExecution{
+		Result: resultName,
+		Steps:  []*manifestStep{},
+		Units:  res.Units,
+		Events: res.Events,
+	}
+	for _, step := range res.Steps {
+		if step.End.IsZero() {
+			continue
+		}
+		if e.StartTime.IsZero() {
+			e.StartTime = step.Start
+		}
+		e.Steps = append(e.Steps, &manifestStep{
+			Phase:      step.Phase,
+			StartTime:  step.Start,
+			DurationMs: float64(step.Duration()) / float64(time.Millisecond),
+			ExitCode:   step.ExitCode,
+			TimedOut:   step.TimedOut,
+		})
+	}
+
+	b.m.lock.Lock()
+	b.Executions = append(b.Executions, e)
+	b.m.lock.Unlock()
+	return b.m.write()
+}
+
+// finish records the end of the invocation of the runner and its exit code.
+// It is safe to call on a nil manifest.
+func (m *manifest) finish(code int) error {
+	if m == nil {
+		return nil
+	}
+	m.lock.Lock()
+	end := time.Now()
+	m.EndTime = &end
+	m.ExitCode = &code
+	m.lock.Unlock()
+	return m.write()
+}
+
+// write writes the manifest to manifest.json in the run directory.
+func (m *manifest) write() error {
+	m.lock.Lock()
+	out, err := json.MarshalIndent(m, "", "  ")
+	m.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(m.path, append(out, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed writing manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// Dimensions of the chart in an HTML report, in pixels.
+const (
+	chartWidth   = 900
+	chartHeight  = 420
+	chartMarginL = 70
+	chartMarginR = 20
+	chartMarginT = 20
+	chartMarginB = 40
+	chartTicks   = 5
+)
+
+// chartColors are assigned to the series of a chart in order.
+var chartColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+// chartSeries is a single line drawn on a chart.
+type chartSeries struct {
+	Name  string
+	Color string
+	Path  string
+}
+
+// chartTick is a labelled position along an axis of a chart.
+type chartTick struct {
+	Pos   float64
+	Label string
+}
+
+// chartMarker is an event overlaid on a chart as a vertical line, in the
+// color of the series it belongs to. Label refers to the row of the event in
+// the table of events, and Title is shown when hovering over it.
+type chartMarker struct {
+	Pos   float64
+	Color string
+	Label string
+	Title string
+}
+
+// reportData is passed to the report template.
+type reportData struct {
+	Title   string
+	Width   int
+	Height  int
+	Left    int
+	Right   int
+	Top     int
+	Bottom  int
+	XTicks  []chartTick
+	YTicks  []chartTick
+	Series  []chartSeries
+	Markers []chartMarker
+	Header  []string
+	Rows    [][]string
+
+	// EventHeader and EventRows are the table of the events, if there are
+	// any.
+	EventHeader []string
+	EventRows   [][]string
+}
+
+// writeReport renders a self-contained HTML report to the file at path. The
+// report charts the named curves on a common timeline, with the events of
+// each curve overlaid, followed by a table with the given header and rows
+// and a table of the events. The timeline extends before the origin if any
+// point or event precedes it.
+func writeReport(path, title string, names []string, curves [][]point, events [][]*bench.Event, header []string, rows [][]string) error {
+	data := &reportData{
+		Title:  title,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Left:   chartMarginL,
+		Right:  chartWidth - chartMarginR,
+		Top:    chartMarginT,
+		Bottom: chartHeight - chartMarginB,
+		Header: header,
+		Rows:   rows,
+	}
+
+	// Determine the extent of the axes
+	var minX, maxX int64 = 0, 1
+	maxY := 1.0
+	extend := func(elapsed int64) {
+		if elapsed < minX {
+			minX = elapsed
+		}
+		if elapsed > maxX {
+			maxX = elapsed
+		}
+	}
+	for _, es := range events {
+		for _, e := range es {
+			extend(e.Elapsed)
+		}
+	}
+	for _, c := range curves {
+		for _, p := range c {
+			extend(p.elapsed)
+			if p.value > maxY {
+				maxY = p.value
+			}
+		}
+	}
+
+	// Positions are rounded to a tenth of a pixel to keep the report small
+	scaleX := func(elapsed int64) float64 {
+		frac := float64(elapsed-minX) / float64(maxX-minX)
+		x := float64(data.Left) + frac*float64(data.Right-data.Left)
+		return math.Round(x*10) / 10
+	}
+	scaleY := func(value float64) float64 {
+		y := float64(data.Bottom) - value/maxY*float64(data.Bottom-data.Top)
+		return math.Round(y*10) / 10
+	}
+
+	for i := 0; i <= chartTicks; i++ {
+		frac := float64(i) / chartTicks
+		elapsed := minX + int64(frac*float64(maxX-minX))
+		data.XTicks = append(data.XTicks, chartTick{
+			Pos:   scaleX(elapsed),
+			Label: fmt.Sprintf("%.1fs", float64(elapsed)/1000),
+		})
+		data.YTicks = append(data.YTicks, chartTick{
+			Pos:   scaleY(frac * maxY),
+			Label: fmt.Sprintf("%.4g", frac*maxY),
+		})
+	}
+
+	// Draw each curve as a step function, holding each value until the next
+	for i, c := range curves {
+		var d strings.Builder
+		fmt.Fprintf(&d, "M %.1f %.1f", scaleX(minX), scaleY(0))
+		for _, p := range c {
+			fmt.Fprintf(&d, " H %.1f V %.1f", scaleX(p.elapsed), scaleY(p.value))
+		}
+		fmt.Fprintf(&d, " H %.1f", scaleX(maxX))
+		data.Series = append(data.Series, chartSeries{
+			Name:  names[i],
+			Color: chartColors[i%len(chartColors)],
+			Path:  d.String(),
+		})
+	}
+
+	// Number the events in order of the curves, and then of time
+	for i, es := range events {
+		for _, e := range es {
+			n := strconv.Itoa(len(data.EventRows) + 1)
+			desc := e.Message
+			if e.Name != "" {
+				desc = e.Name + ": " + desc
+			}
+			data.EventRows = append(data.EventRows, []string{
+				n,
+				names[i],
+				fmt.Sprintf("%.3f", float64(e.Elapsed)/1000),
+				bench.SeriesName(e.Name, e.Labels),
+				e.Message,
+			})
+			data.Markers = append(data.Markers, chartMarker{
+				Pos:   scaleX(e.Elapsed),
+				Color: chartColors[i%len(chartColors)],
+				Label: n,
+				Title: fmt.Sprintf("%s at %.1fs (%s)", desc, float64(e.Elapsed)/1000, names[i]),
+			})
+		}
+	}
+	if len(data.EventRows) != 0 {
+		data.EventHeader = []string{"#", header[0], "elapsed_s", "name", "message"}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := reportTemplate.Execute(buf, data); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeResultReport writes an HTML report of a single result to the file at
+// path, charting its running tasks with its events overlaid, followed by a
+// table of its derived metrics.
+func writeResultReport(path, name string, res *result) error {
+	header := append([]string{"result"}, summaryMetrics...)
+	row := summaryRow([]string{name}, []*result{res})
+
+	// The count of iterations is dropped, as there is only the one
+	row = append(row[:1], row[2:]...)
+	curves := [][]point{curve(res.totals, "running")}
+	events := [][]*bench.Event{res.Events}
+	return writeReport(path, "Running tasks of "+name, []string{name}, curves, events, header, [][]string{row})
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-top: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+table.events td:nth-child(n+4) { text-align: left; }
+.legend span { display: inline-block; margin-right: 1.5em; }
+.legend i { display: inline-block; width: 1em; height: 0.3em; margin-right: 0.4em; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<svg width="{{.Width}}" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg" font-size="11">
+<line x1="{{.Left}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}" stroke="#000"/>
+<line x1="{{.Left}}" y1="{{.Top}}" x2="{{.Left}}" y2="{{.Bottom}}" stroke="#000"/>
+{{- range .XTicks}}
+<line x1="{{.Pos}}" y1="{{$.Top}}" x2="{{.Pos}}" y2="{{$.Bottom}}" stroke="#eee"/>
+<text x="{{.Pos}}" y="{{$.Bottom}}" dy="16" text-anchor="middle">{{.Label}}</text>
+{{- end}}
+{{- range .YTicks}}
+<line x1="{{$.Left}}" y1="{{.Pos}}" x2="{{$.Right}}" y2="{{.Pos}}" stroke="#eee"/>
+<text x="{{$.Left}}" y="{{.Pos}}" dx="-6" dy="4" text-anchor="end">{{.Label}}</text>
+{{- end}}
+{{- range .Series}}
+<path d="{{.Path}}" fill="none" stroke="{{.Color}}" stroke-width="1.5"/>
+{{- end}}
+{{- range .Markers}}
+<g><title>{{.Title}}</title>
+<line x1="{{.Pos}}" y1="{{$.Top}}" x2="{{.Pos}}" y2="{{$.Bottom}}" stroke="{{.Color}}" stroke-dasharray="4 3"/>
+<text x="{{.Pos}}" y="{{$.Top}}" dx="3" dy="10" fill="{{.Color}}">{{.Label}}</text>
+</g>
+{{- end}}
+</svg>
+<div class="legend">
+{{- range .Series}}
+<span><i style="background: {{.Color}}"></i>{{.Name}}</span>
+{{- end}}
+</div>
+<table>
+<tr>{{range .Header}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- if .EventRows}}
+<h2>Events</h2>
+<table class="events">
+<tr>{{range .EventHeader}}<th>{{.}}</th>{{end}}</tr>
+{{- range .EventRows}}
+<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+func TestReport_BeforeOrigin(t *testing.T) {
+	// A point and an event precede the origin, which must still be drawn
+	// within the chart.
+	path := filepath.Join(t.TempDir(), "report.html")
+	curves := [][]point{{{elapsed: -500, value: 1}, {elapsed: 1000, value: 2}}}
+	events := [][]*bench.Event{{{Name: "early", Message: "before run", Elapsed: -200}}}
+	header := []string{"result", "peak_running"}
+	if err := writeReport(path, "test", []string{"result"}, curves, events, header, [][]string{{"result", "2"}}); err != nil {
+		t.Fatalf("failed writing report: %v", err)
+	}
+	out, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed reading report: %v", err)
+	}
+
+	positions := regexp.MustCompile(`(?: x1="| x2="|[MH] )(-?[0-9.]+)`).FindAllStringSubmatch(string(out), -1)
+	if len(positions) == 0 {
+		t.Fatalf("expected positions in the report:\n%s", out)
+	}
+	for _, m := range positions {
+		x, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			t.Fatalf("invalid position %q: %v", m[0], err)
+		}
+		if x < chartMarginL || x > chartWidth-chartMarginR {
+			t.Fatalf("position %q is outside the chart", m[0])
+		}
+	}
+	if !strings.Contains(string(out), "stroke-dasharray") {
+		t.Fatalf("expected the event to be drawn on the chart")
+	}
+}
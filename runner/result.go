@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// writeResult takes a time-indexed map of metrics and formats them into
+// a CSV format. The data is then flushed to a file at the given path with the
+// suffix and a .csv extension added, such as result.csv. If the benchmark did
+// not complete, the suffix marks the partial data, as in result.incomplete.csv.
+func writeResult(name string, metrics map[int64]map[string]float64, suffix string) error {
+	// Create the output buffer and CSV writer.
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+
+	// Get the unique names of the data fields. These will be the names
+	// of the columns in the CSV output.
+	fieldsMap := make(map[string]struct{})
+	for _, events := range metrics {
+		for name, _ := range events {
+			fieldsMap[name] = struct{}{}
+		}
+	}
+	fields := make([]string, 0, len(fieldsMap))
+	for field, _ := range fieldsMap {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	// Write the field names as a header row.
+	csvWriter.Write(append([]string{"elapsed_ms"}, fields...))
+
+	// Used to record the last values as we iterate, making it possible to fill
+	// in all known data fields at each known timestamp.
+	last := make(map[string]float64, len(fields))
+
+	// Sort events by timestamp
+	var times []int64
+	for time, _ := range metrics {
+		times = append(times, time)
+	}
+	sort.Sort(Int64Sort(times))
+
+	for _, ts := range times {
+		records := make([]string, len(fields)+1)
+
+		// Log the elapsed time
+		records[0] = strconv.FormatInt(ts, 10)
+
+		// Go over the events for the given time, using the field
+		// header mappings to ensure we correctly order the columns.
+		events := metrics[ts]
+		for i, field := range fields {
+			if value, ok := events[field]; ok {
+				last[field] = value
+			}
+			records[i+1] = strconv.FormatFloat(last[field], 'f', -1, 64)
+		}
+
+		// Flush the line to the CSV encoder
+		csvWriter.Write(records)
+	}
+
+	// Flush the lines to the buffer and check for write errors
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+
+	// Create the output file
+	path := name + suffix + ".csv"
+	fh, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed creating result file: %v", err)
+	}
+	defer fh.Close()
+
+	// Copy the buffer onto the file handle
+	if _, err := io.Copy(fh, buf); err != nil {
+		return fmt.Errorf("failed writing result file: %v", err)
+	}
+
+	log.Printf("[INFO] runner: results written to %s", path)
+	return nil
+}
+
+// writeSamples writes the samples of each distribution as CSV to a file at
+// the given path with the suffix and a .samples.csv extension added, such as
+// result.samples.csv. Each row holds a single sample, in time order.
+func writeSamples(name string, samples bench.Samples, suffix string) error {
+	type row struct {
+		series string
+		sample bench.Sample
+	}
+	var rows []row
+	for series, s := range samples {
+		for _, sample := range s {
+			rows = append(rows, row{series: series, sample: sample})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].sample.Elapsed != rows[j].sample.Elapsed {
+			return rows[i].sample.Elapsed < rows[j].sample.Elapsed
+		}
+		return rows[i].series < rows[j].series
+	})
+
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write([]string{"elapsed_ms", "series", "value"})
+	for _, r := range rows {
+		csvWriter.Write([]string{
+			strconv.FormatInt(r.sample.Elapsed, 10),
+			r.series,
+			strconv.FormatFloat(r.sample.Value, 'f', -1, 64),
+		})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+
+	path := name + suffix + ".samples.csv"
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed writing samples file: %v", err)
+	}
+	log.Printf("[INFO] runner: samples written to %s", path)
+	return nil
+}
+
+// writeEvents writes the events emitted by the implementation as CSV to a
+// file at the given path with the suffix and a .events.csv extension added,
+// such as result.events.csv. Labels are given in the same form as in the
+// names of series, such as "class=class_1,node=abc".
+func writeEvents(name string, events []*bench.Event, suffix string) error {
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write([]string{"elapsed_ms", "name", "message", "labels", "time"})
+	for _, e := range events {
+		labels := strings.TrimSuffix(strings.TrimPrefix(bench.SeriesName("", e.Labels), "{"), "}")
+		csvWriter.Write([]string{
+			strconv.FormatInt(e.Elapsed, 10),
+			e.Name,
+			e.Message,
+			labels,
+			e.Time.UTC().Format(bench.LogTimeFormat),
+		})
+	}
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+
+	path := name + suffix + ".events.csv"
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed writing events file: %v", err)
+	}
+	log.Printf("[INFO] runner: events written to %s", path)
+	return nil
+}
+
+// Int64Sort is used to sort slices of int64 numbers
+type Int64Sort []int64
+
+func (s Int64Sort) Len() int {
+	return len(s)
+}
+
+func (s Int64Sort) Less(a, b int) bool {
+	return s[a] < s[b]
+}
+
+func (s Int64Sort) Swap(a, b int) {
+	s[a], s[b] = s[b], s[a]
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// tCritical95 holds the two-tailed critical values of Student's
+// t-distribution at 95% confidence, indexed by degrees of freedom. Larger
+// samples use the normal approximation.
+var tCritical95 = []float64{
+	0, 12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262,
+	2.228, 2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093,
+	2.086, 2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045,
+	2.042,
+}
+
+// aggregate holds statistics about a single derived metric across several
+// executions of a benchmark.
+type aggregate struct {
+	name   string
+	n      int
+	mean   float64
+	stddev float64
+	min    float64
+	max    float64
+
+	// ciLow and ciHigh bound the 95% confidence interval of the mean.
+	ciLow  float64
+	ciHigh float64
+}
+
+// aggregateSummaries computes statistics for each derived metric over the
+// given summaries. Metrics missing from some summaries are aggregated over
+// the summaries which contain them. The result is sorted by metric name.
+func aggregateSummaries(summaries []map[string]float64) []*aggregate {
+	samples := make(map[string][]float64)
+	for _, summary := range summaries {
+		for name, value := range summary {
+			samples[name] = append(samples[name], value)
+		}
+	}
+
+	aggs := make([]*aggregate, 0, len(samples))
+	for name, values := range samples {
+		aggs = append(aggs, newAggregate(name, values))
+	}
+	sort.Sort(aggregateSort(aggs))
+	return aggs
+}
+
+// newAggregate computes the statistics of a non-empty set of samples.
+func newAggregate(name string, values []float64) *aggregate {
+	agg := &aggregate{
+		name: name,
+		n:    len(values),
+		min:  values[0],
+		max:  values[0],
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += v
+		agg.min = math.Min(agg.min, v)
+		agg.max = math.Max(agg.max, v)
+	}
+	agg.mean = sum / float64(agg.n)
+
+	// Use the sample standard deviation, which is undefined for a single
+	// sample. The confidence interval collapses to the mean in that case.
+	agg.ciLow, agg.ciHigh = agg.mean, agg.mean
+	if agg.n < 2 {
+		return agg
+	}
+	var sqDiff float64
+	for _, v := range values {
+		sqDiff += (v - agg.mean) * (v - agg.mean)
+	}
+	agg.stddev = math.Sqrt(sqDiff / float64(agg.n-1))
+
+	t := 1.96
+	if df := agg.n - 1; df < len(tCritical95) {
+		t = tCritical95[df]
+	}
+	margin := t * agg.stddev / math.Sqrt(float64(agg.n))
+	agg.ciLow, agg.ciHigh = agg.mean-margin, agg.mean+margin
+	return agg
+}
+
+// writeAggregates formats the aggregated statistics as CSV and writes them
+// to the file at path.
+func writeAggregates(path string, aggs []*aggregate) error {
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write([]string{
+		"metric", "n", "mean", "stddev", "min", "max", "ci95_low", "ci95_high",
+	})
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	for _, agg := range aggs {
+		csvWriter.Write([]string{
+			agg.name,
+			strconv.Itoa(agg.n),
+			format(agg.mean),
+			format(agg.stddev),
+			format(agg.min),
+			format(agg.max),
+			format(agg.ciLow),
+			format(agg.ciHigh),
+		})
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed writing aggregate file: %v", err)
+	}
+
+	log.Printf("[INFO] runner: aggregate statistics written to %s", path)
+	return nil
+}
+
+// aggregateSort is used to sort aggregates by metric name.
+type aggregateSort []*aggregate
+
+func (s aggregateSort) Len() int {
+	return len(s)
+}
+
+func (s aggregateSort) Less(a, b int) bool {
+	return s[a].name < s[b].name
+}
+
+func (s aggregateSort) Swap(a, b int) {
+	s[a], s[b] = s[b], s[a]
+}
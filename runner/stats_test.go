@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNewAggregate(t *testing.T) {
+	many := make([]float64, 40)
+	for i := range many {
+		many[i] = float64(i % 2)
+	}
+	cases := []struct {
+		name   string
+		values []float64
+
+		mean, stddev, min, max float64
+
+		// t is the critical value the confidence interval is expected to
+		// use, or zero if it should collapse to the mean.
+		t float64
+	}{
+		{"single", []float64{5}, 5, 0, 5, 5, 0},
+		{"pair", []float64{1, 3}, 2, math.Sqrt(2), 1, 3, 12.706},
+		{"small", []float64{2, 4, 6}, 4, 2, 2, 6, 4.303},
+		{"identical", []float64{7, 7, 7, 7}, 7, 0, 7, 7, 3.182},
+		{"large", many, 0.5, math.Sqrt(10.0 / 39), 0, 1, 1.96},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			agg := newAggregate("m", c.values)
+			margin := c.t * c.stddev / math.Sqrt(float64(len(c.values)))
+			got := []float64{agg.mean, agg.stddev, agg.min, agg.max, agg.ciLow, agg.ciHigh}
+			want := []float64{c.mean, c.stddev, c.min, c.max, c.mean - margin, c.mean + margin}
+			for i := range got {
+				if math.Abs(got[i]-want[i]) > 1e-9 {
+					t.Fatalf("expected %v, got %v", want, got)
+				}
+			}
+			if agg.n != len(c.values) {
+				t.Fatalf("expected n of %d, got %d", len(c.values), agg.n)
+			}
+		})
+	}
+}
+
+func TestAggregateSummaries(t *testing.T) {
+	// Metrics missing from some summaries are aggregated over the rest
+	aggs := aggregateSummaries([]map[string]float64{
+		{"b": 1, "a": 2},
+		{"b": 3},
+	})
+	if len(aggs) != 2 || aggs[0].name != "a" || aggs[1].name != "b" {
+		t.Fatalf("expected aggregates of a and b in order, got %v", aggs)
+	}
+	if aggs[0].n != 1 || aggs[0].ciLow != 2 || aggs[0].ciHigh != 2 {
+		t.Fatalf("expected a single sample of a without an interval, got %+v", aggs[0])
+	}
+	if aggs[1].n != 2 || aggs[1].mean != 2 {
+		t.Fatalf("expected two samples of b with a mean of 2, got %+v", aggs[1])
+	}
+}
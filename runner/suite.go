@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// suite is a campaign of benchmarks, decoded from a JSON suite file. Any
+// settings given at the top level of the suite apply to every benchmark
+// which does not override them.
+//
+// Relative paths in the suite, including the output directory, the path to
+// each implementation and any paths passed to it in its environment, are
+// resolved against the working directory of the runner rather than the
+// directory of the suite file, since implementations are executed in the
+// working directory of the runner.
+type suite struct {
+	// Output is the directory results are written to. Each benchmark
+	// writes to a sub-directory of it.
+	Output string `json:"output"`
+
+	Iterations int               `json:"iterations"`
+	Warmup     int               `json:"warmup"`
+	Cooldown   duration          `json:"cooldown"`
+	Timeouts   suiteTimeouts     `json:"timeouts"`
+	Env        map[string]string `json:"env"`
+
+	// Origin is the step from whose start elapsed time is measured.
+	Origin string `json:"origin"`
+
+	// Filter and GroupBy select and regroup the series of the results by
+	// their labels, as the -filter and -group-by flags do.
+	Filter  map[string]string `json:"filter"`
+	GroupBy []string          `json:"group_by"`
+
+	// Compare writes a comparison of the results of every benchmark which
+	// does not sweep over parameters, once all have executed.
+	Compare bool `json:"compare"`
+
+	Benchmarks []*suiteBenchmark `json:"benchmarks"`
+
+	// logs and manifest enable capturing the output of each step and
+	// recording every benchmark executed, if an output directory was given
+	// to the runner.
+	logs     bool
+	manifest *manifest
+}
+
+// suiteBenchmark is a single test implementation to execute as part of a
+// suite, along with its configuration.
+type suiteBenchmark struct {
+	// Name identifies the benchmark, and is also the default name of the
+	// sub-directory its results are written to.
+	Name string `json:"name"`
+
+	// Path is the path to the test implementation executable.
+	Path string `json:"path"`
+
+	// Output overrides the sub-directory results are written to.
+	Output string `json:"output"`
+
+	// Env is merged over the environment variables of the suite, and Sweep
+	// holds environment variables whose values are swept over.
+	Env   map[string]string   `json:"env"`
+	Sweep map[string][]string `json:"sweep"`
+
+	Iterations int           `json:"iterations"`
+	Warmup     *int          `json:"warmup"`
+	Cooldown   *duration     `json:"cooldown"`
+	Timeouts   suiteTimeouts `json:"timeouts"`
+
+	// Filter is merged over the label filter of the suite, and GroupBy
+	// overrides how the series of the suite are grouped.
+	Filter  map[string]string `json:"filter"`
+	GroupBy []string          `json:"group_by"`
+}
+
+// suiteTimeouts holds the timeouts of each step. Unset timeouts are
+// inherited, and otherwise disabled.
+type suiteTimeouts struct {
+	Setup    *duration `json:"setup"`
+	Run      *duration `json:"run"`
+	Status   *duration `json:"status"`
+	Validate *duration `json:"validate"`
+	Teardown *duration `json:"teardown"`
+}
+
+// duration is a time.Duration which is decoded from a JSON string such as
+// "90s" or "10m".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"90s\": %v", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = duration(v)
+	return nil
+}
+
+// loadSuite reads and validates the suite file at path.
+func loadSuite(path string) (*suite, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening suite file: %v", err)
+	}
+	defer fh.Close()
+
+	s := &suite{}
+	dec := json.NewDecoder(fh)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(s); err != nil {
+		return nil, fmt.Errorf("failed parsing suite file %q: %v", path, err)
+	}
+
+	if s.Iterations < 0 || s.Warmup < 0 {
+		return nil, fmt.Errorf("suite file %q has negative iterations", path)
+	}
+	switch s.Origin {
+	case "", bench.OriginRun, bench.OriginStatus:
+	default:
+		return nil, fmt.Errorf("suite file %q has invalid origin %q", path, s.Origin)
+	}
+	if len(s.Benchmarks) == 0 {
+		return nil, fmt.Errorf("suite file %q has no benchmarks", path)
+	}
+	names := make(map[string]struct{}, len(s.Benchmarks))
+	for i, b := range s.Benchmarks {
+		switch {
+		case b.Name == "":
+			return nil, fmt.Errorf("benchmark %d in suite has no name", i+1)
+		case b.Path == "":
+			return nil, fmt.Errorf("benchmark %q in suite has no path", b.Name)
+		case b.Iterations < 0 || (b.Warmup != nil && *b.Warmup < 0):
+			return nil, fmt.Errorf("benchmark %q in suite has negative iterations", b.Name)
+		}
+		if err := checkDirName(b.Name); err != nil {
+			return nil, fmt.Errorf("benchmark %q in suite has an invalid name: %v", b.Name, err)
+		}
+		if b.Output != "" {
+			if err := checkDirName(b.Output); err != nil {
+				return nil, fmt.Errorf("benchmark %q in suite has an invalid output: %v", b.Name, err)
+			}
+		}
+		for name, values := range b.Sweep {
+			if err := checkSweepName(name); err != nil {
+				return nil, fmt.Errorf("benchmark %q in suite sweeps over an invalid name: %v", b.Name, err)
+			}
+			if len(values) == 0 {
+				return nil, fmt.Errorf("benchmark %q in suite sweeps over %q with no values", b.Name, name)
+			}
+			for _, v := range values {
+				if v == "" {
+					return nil, fmt.Errorf("benchmark %q in suite sweeps over %q with an empty value", b.Name, name)
+				}
+			}
+		}
+		if _, ok := names[b.Name]; ok {
+			return nil, fmt.Errorf("benchmark %q in suite is defined twice", b.Name)
+		}
+		names[b.Name] = struct{}{}
+	}
+	return s, nil
+}
+
+// checkDirName returns an error if the name may not be used as the name of
+// a sub-directory of the output directory, since it would refer to a
+// directory outside of it.
+func checkDirName(name string) error {
+	switch {
+	case filepath.IsAbs(name):
+		return fmt.Errorf("%q is an absolute path", name)
+	case strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, filepath.Separator):
+		return fmt.Errorf("%q contains a path separator", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("%q contains \"..\"", name)
+	}
+	return nil
+}
+
+// config returns the configuration of the benchmark, with the settings of
+// the suite applied where the benchmark does not override them.
+func (b *suiteBenchmark) config(s *suite) *config {
+	conf := &config{
+		path:       b.Path,
+		dir:        filepath.Join(s.Output, b.Name),
+		iterations: 1,
+		warmup:     s.Warmup,
+		cooldown:   time.Duration(s.Cooldown),
+		origin:     s.Origin,
+		groupBy:    s.GroupBy,
+		logs:       s.logs,
+		manifest:   s.manifest,
+	}
+	if b.Output != "" {
+		conf.dir = filepath.Join(s.Output, b.Output)
+	}
+	if s.Iterations != 0 {
+		conf.iterations = s.Iterations
+	}
+	if b.Iterations != 0 {
+		conf.iterations = b.Iterations
+	}
+	if b.Warmup != nil {
+		conf.warmup = *b.Warmup
+	}
+	if b.Cooldown != nil {
+		conf.cooldown = time.Duration(*b.Cooldown)
+	}
+	if b.GroupBy != nil {
+		conf.groupBy = b.GroupBy
+	}
+	if len(s.Filter)+len(b.Filter) != 0 {
+		conf.filter = make(map[string]string, len(s.Filter)+len(b.Filter))
+		for k, v := range s.Filter {
+			conf.filter[k] = v
+		}
+		for k, v := range b.Filter {
+			conf.filter[k] = v
+		}
+	}
+
+	timeout := func(suite, override *duration) time.Duration {
+		switch {
+		case override != nil:
+			return time.Duration(*override)
+		case suite != nil:
+			return time.Duration(*suite)
+		}
+		return 0
+	}
+	conf.timeouts.Setup = timeout(s.Timeouts.Setup, b.Timeouts.Setup)
+	conf.timeouts.Run = timeout(s.Timeouts.Run, b.Timeouts.Run)
+	conf.timeouts.Status = timeout(s.Timeouts.Status, b.Timeouts.Status)
+	conf.timeouts.Validate = timeout(s.Timeouts.Validate, b.Timeouts.Validate)
+	conf.timeouts.Teardown = timeout(s.Timeouts.Teardown, b.Timeouts.Teardown)
+
+	// Merge the environment, sorting for a stable order
+	env := make(map[string]string, len(s.Env)+len(b.Env))
+	for k, v := range s.Env {
+		env[k] = v
+	}
+	for k, v := range b.Env {
+		env[k] = v
+	}
+	for k, v := range env {
+		conf.env = append(conf.env, k+"="+v)
+	}
+	sort.Strings(conf.env)
+	return conf
+}
+
+// sweep returns the parameters swept over by the benchmark, sorted by name.
+func (b *suiteBenchmark) sweep() []*sweepParam {
+	params := make([]*sweepParam, 0, len(b.Sweep))
+	for name, values := range b.Sweep {
+		params = append(params, &sweepParam{name: name, values: values})
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].name < params[j].name
+	})
+	return params
+}
+
+// runSuite executes each benchmark of the suite in order. A failed benchmark
+// does not prevent the remaining benchmarks from executing, unless the
+// runner was interrupted. Returns the exit code of the runner, reflecting
+// the first failure.
+func runSuite(s *suite, sup *supervisor) int {
+	cmp := &comparison{}
+	code := exitCodeOK
+	for i, b := range s.Benchmarks {
+		conf := b.config(s)
+		if i > 0 {
+			if err := sup.Sleep(conf.cooldown); err != nil {
+				code = signalExitCode(sup.Interrupted())
+				break
+			}
+		}
+		log.Printf("[INFO] runner: starting benchmark %q (%d of %d)",
+			b.Name, i+1, len(s.Benchmarks))
+
+		benchCode := exitCodeOK
+		if err := checkExecutable(conf.path); err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			benchCode = exitCodeFailed
+		} else if err := os.MkdirAll(conf.dir, 0755); err != nil {
+			log.Printf("[ERR] runner: failed creating result directory: %v", err)
+			benchCode = exitCodeFailed
+		} else if params := b.sweep(); len(params) != 0 {
+			benchCode = runSweep(conf, params, sup)
+		} else {
+			var results []*result
+			benchCode, results = runIterations(conf, sup)
+			cmp.add(b.Name, results)
+		}
+
+		if benchCode != exitCodeOK {
+			log.Printf("[ERR] runner: benchmark %q failed", b.Name)
+			if code == exitCodeOK {
+				code = benchCode
+			}
+		}
+		if sig := sup.Interrupted(); sig != nil {
+			code = signalExitCode(sig)
+			break
+		}
+	}
+
+	if s.Compare {
+		if err := cmp.write(s.Output, code == exitCodeOK); err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			if code == exitCodeOK {
+				code = exitCodeFailed
+			}
+		}
+	}
+	return code
+}
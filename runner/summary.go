@@ -0,0 +1,106 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// percentiles are the fractions of the peak number of running tasks for
+// which the time taken to reach them is derived, and the percentiles derived
+// from the samples of each distribution.
+var percentiles = []struct {
+	name string
+	frac float64
+}{
+	{"p50", 0.50},
+	{"p95", 0.95},
+	{"p99", 0.99},
+}
+
+// summarize derives scalar metrics from the time-indexed metrics of a single
+// benchmark execution. These are the values which can be compared between
+// executions, such as the time taken for all tasks to start running. Times
+// are given in milliseconds. If the number of tasks the implementation
+// expected to run is known, the percentiles are relative to it, and
+// otherwise to the peak number of running tasks.
+func summarize(metrics map[int64]map[string]float64, expected int) map[string]float64 {
+	summary := make(map[string]float64)
+
+	// Sort events by timestamp
+	var times []int64
+	for time := range metrics {
+		times = append(times, time)
+	}
+	sort.Sort(Int64Sort(times))
+
+	// Find the peak number of running tasks, along with the final value of
+	// every metric.
+	var peak float64
+	for _, ts := range times {
+		for name, value := range metrics[ts] {
+			summary["final_"+name] = value
+			if name == "running" && value > peak {
+				peak = value
+			}
+		}
+	}
+	summary["peak_running"] = peak
+	if peak == 0 {
+		return summary
+	}
+
+	// Find the first time the number of running tasks reached each of the
+	// thresholds. Thresholds which were never reached are left out.
+	all := peak
+	if expected > 0 {
+		all = float64(expected)
+	}
+	thresholds := map[string]float64{
+		"time_to_first_running_ms": 1,
+		"time_to_all_running_ms":   all,
+	}
+	for _, p := range percentiles {
+		thresholds["time_to_"+p.name+"_running_ms"] = p.frac * all
+	}
+	for _, ts := range times {
+		value, ok := metrics[ts]["running"]
+		if !ok {
+			continue
+		}
+		for name, threshold := range thresholds {
+			if _, ok := summary[name]; !ok && value >= threshold {
+				summary[name] = float64(ts)
+			}
+		}
+	}
+	return summary
+}
+
+// summarizeSamples adds the statistics of the samples of each distribution
+// to the summary, such as "p99_latency_ms" for the 99th percentile of the
+// samples of latency_ms. Percentiles use the nearest-rank method.
+func summarizeSamples(summary map[string]float64, samples bench.Samples) {
+	for name, series := range samples {
+		if len(series) == 0 {
+			continue
+		}
+		values := make([]float64, len(series))
+		var sum float64
+		for i, sample := range series {
+			values[i] = sample.Value
+			sum += sample.Value
+		}
+		sort.Float64s(values)
+
+		summary["count_"+name] = float64(len(values))
+		summary["mean_"+name] = sum / float64(len(values))
+		summary["min_"+name] = values[0]
+		summary["max_"+name] = values[len(values)-1]
+		for _, p := range percentiles {
+			rank := int(math.Ceil(p.frac * float64(len(values))))
+			summary[p.name+"_"+name] = values[rank-1]
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// supervisor traps the signals received by the runner and interrupts the
+// benchmarks being executed. A single supervisor is shared by every
+// benchmark executed by the runner, so that a signal stops all remaining
+// work.
+type supervisor struct {
+	*bench.Supervisor
+
+	sigCh  chan os.Signal
+	doneCh chan struct{}
+}
+
+// newSupervisor creates a supervisor and starts trapping SIGINT and SIGTERM.
+// Callers must call stop once no more sub-commands will be executed.
+func newSupervisor() *supervisor {
+	s := &supervisor{
+		Supervisor: bench.NewSupervisor(),
+		sigCh:      make(chan os.Signal, 1),
+		doneCh:     make(chan struct{}),
+	}
+	signal.Notify(s.sigCh, os.Interrupt, syscall.SIGTERM)
+	go s.handleSignals()
+	return s
+}
+
+// stop restores the default signal handling.
+func (s *supervisor) stop() {
+	signal.Stop(s.sigCh)
+	close(s.doneCh)
+}
+
+// handleSignals forwards signals received by the runner to the process
+// groups of the active sub-commands. Since each sub-command runs in its own
+// process group, it would otherwise never see a Ctrl-C from the terminal.
+// The first signal is forwarded as-is, and any further signals kill the
+// active sub-commands outright. Returns when stop is called.
+func (s *supervisor) handleSignals() {
+	for {
+		select {
+		case sig := <-s.sigCh:
+			if s.Interrupted() == nil {
+				log.Printf("[INFO] runner: received %v; stopping benchmark", sig)
+			} else {
+				log.Printf("[WARN] runner: received %v again; killing active steps", sig)
+			}
+			s.Interrupt(sig.(syscall.Signal))
+
+		case <-s.doneCh:
+			return
+		}
+	}
+}
+
+// signalExitCode returns the exit code of the runner when interrupted by the
+// given signal.
+func signalExitCode(sig os.Signal) int {
+	return exitCodeSignal + int(sig.(syscall.Signal))
+}
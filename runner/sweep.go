@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// summaryMetrics are the derived metrics reported for each combination in
+// the summary of a parameter sweep, and for each implementation in a
+// comparison.
+var summaryMetrics = []string{
+	"peak_running",
+	"time_to_first_running_ms",
+	"time_to_p50_running_ms",
+	"time_to_p95_running_ms",
+	"time_to_p99_running_ms",
+	"time_to_all_running_ms",
+}
+
+// sweepParam is an environment variable along with the values the
+// benchmark is executed with.
+type sweepParam struct {
+	name   string
+	values []string
+}
+
+// sweepFlag implements flag.Value, collecting each -sweep flag given.
+type sweepFlag []*sweepParam
+
+func (f *sweepFlag) String() string {
+	return ""
+}
+
+// Set parses a sweep definition of the form KEY=value1,value2,...
+func (f *sweepFlag) Set(value string) error {
+	idx := strings.Index(value, "=")
+	if idx < 1 {
+		return fmt.Errorf("sweep must be of the form KEY=value1,value2,...")
+	}
+	param := &sweepParam{
+		name:   value[:idx],
+		values: strings.Split(value[idx+1:], ","),
+	}
+	if err := checkSweepName(param.name); err != nil {
+		return fmt.Errorf("sweep has an invalid name: %v", err)
+	}
+	for _, v := range param.values {
+		if v == "" {
+			return fmt.Errorf("sweep over %q has an empty value", param.name)
+		}
+	}
+	*f = append(*f, param)
+	return nil
+}
+
+// checkSweepName returns an error if the name may not be used as the name
+// of an environment variable swept over, since it could not be passed to
+// the implementation as "name=value".
+func checkSweepName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("name is empty")
+	case strings.Contains(name, "="):
+		return fmt.Errorf("%q contains \"=\"", name)
+	case strings.IndexFunc(name, unicode.IsSpace) >= 0:
+		return fmt.Errorf("%q contains whitespace", name)
+	}
+	return nil
+}
+
+// combinations returns every combination of the swept parameter values, as
+// environment variables in the form "key=value". The last parameter varies
+// fastest.
+func combinations(params []*sweepParam) [][]string {
+	combos := [][]string{nil}
+	for _, param := range params {
+		var next [][]string
+		for _, combo := range combos {
+			for _, value := range param.values {
+				env := make([]string, len(combo), len(combo)+1)
+				copy(env, combo)
+				next = append(next, append(env, param.name+"="+value))
+			}
+		}
+		combos = next
+	}
+	return combos
+}
+
+// combinationDir returns the name of the directory the results of a
+// combination are written to, such as "JOBS=10,JOBSPEC=job1.nomad".
+func combinationDir(env []string) string {
+	name := strings.Join(env, ",")
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// runSweep executes the benchmark for every combination of the swept
+// parameters. The results of each combination are written to their own
+// sub-directory of the output directory, and a summary of the derived
+// metrics of every combination is written to sweep.csv and printed.
+// Execution stops at the first failed combination. Returns the exit code of
+// the runner.
+func runSweep(conf *config, params []*sweepParam, sup *supervisor) int {
+	combos := combinations(params)
+	rows := make([][]string, 0, len(combos))
+	code := exitCodeOK
+	for i, env := range combos {
+		if i > 0 {
+			if err := sup.Sleep(conf.cooldown); err != nil {
+				code = signalExitCode(sup.Interrupted())
+				break
+			}
+		}
+		log.Printf("[INFO] runner: starting combination %d of %d: %s",
+			i+1, len(combos), strings.Join(env, " "))
+
+		// Layer the combination on top of the base configuration
+		combo := *conf
+		combo.env = append(append([]string{}, conf.env...), env...)
+		combo.dir = filepath.Join(conf.dir, combinationDir(env))
+		if err := os.MkdirAll(combo.dir, 0755); err != nil {
+			log.Printf("[ERR] runner: failed creating result directory: %v", err)
+			code = exitCodeFailed
+			break
+		}
+
+		var results []*result
+		code, results = runIterations(&combo, sup)
+		if len(results) > 0 {
+			values := make([]string, len(env))
+			for i, kv := range env {
+				values[i] = kv[strings.Index(kv, "=")+1:]
+			}
+			rows = append(rows, summaryRow(values, results))
+		}
+		if code != exitCodeOK {
+			break
+		}
+	}
+
+	if len(rows) > 0 {
+		path := filepath.Join(conf.dir, "sweep.csv")
+		if code != exitCodeOK {
+			path = filepath.Join(conf.dir, "sweep.incomplete.csv")
+		}
+		if err := writeSweep(path, params, rows); err != nil {
+			log.Printf("[ERR] runner: %v", err)
+			if code == exitCodeOK {
+				code = exitCodeFailed
+			}
+		}
+	}
+	return code
+}
+
+// summaryRow formats the summary of the results of a single combination or
+// implementation, which is identified by the leading values of the row. Each
+// derived metric is the mean across the results.
+func summaryRow(keys []string, results []*result) []string {
+	summaries := make([]map[string]float64, len(results))
+	for i, res := range results {
+		summaries[i] = res.summary
+	}
+	means := make(map[string]float64)
+	for _, agg := range aggregateSummaries(summaries) {
+		means[agg.name] = agg.mean
+	}
+
+	row := make([]string, 0, len(keys)+len(summaryMetrics)+1)
+	row = append(row, keys...)
+	row = append(row, strconv.Itoa(len(results)))
+	for _, metric := range summaryMetrics {
+		value, ok := means[metric]
+		if !ok {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return row
+}
+
+// writeSweep writes the summary of a parameter sweep as CSV to the file at
+// path, and prints it as a table.
+func writeSweep(path string, params []*sweepParam, rows [][]string) error {
+	keys := make([]string, len(params))
+	for i, param := range params {
+		keys[i] = param.name
+	}
+	if err := writeSummary(path, keys, rows); err != nil {
+		return fmt.Errorf("failed writing sweep summary: %v", err)
+	}
+	log.Printf("[INFO] runner: sweep summary written to %s", path)
+	return nil
+}
+
+// writeSummary writes rows formatted by summaryRow as CSV to the file at
+// path, and prints them as a table. The keys name the leading columns.
+func writeSummary(path string, keys []string, rows [][]string) error {
+	header := make([]string, 0, len(keys)+len(summaryMetrics)+1)
+	header = append(header, keys...)
+	header = append(header, "iterations")
+	header = append(header, summaryMetrics...)
+
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write(header)
+	csvWriter.WriteAll(rows)
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	// Print the summary so the differences are visible at a glance
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
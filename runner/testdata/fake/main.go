@@ -0,0 +1,174 @@
+// Command fake is a scripted test implementation used by the end-to-end
+// tests of the runner. How it behaves in each step is controlled by the
+// following environment variables:
+//
+//	FAKE_LOG       - File each invoked sub-command is appended to.
+//	FAKE_FAIL      - Comma-separated steps which print to stdout and exit 1.
+//	FAKE_SLEEP     - Comma-separated <step>=<duration> pairs to sleep for.
+//	FAKE_INFO      - Output of the info sub-command, which is otherwise
+//	                 not supported.
+//	FAKE_LINES     - Number of updates of 'running' output by status.
+//	                 Defaults to 3. A comma-separated list gives the number
+//	                 for each invocation of status in turn, as counted in
+//	                 FAKE_LOG.
+//	FAKE_LABELS    - Comma-separated classes, each of which 'running' is
+//	                 output for separately, labeled by class.
+//	FAKE_SAMPLES   - Number of samples 1..n of 'latency' output by status,
+//	                 each along with a delta of 1 of 'started'. May be a
+//	                 list as with FAKE_LINES.
+//	FAKE_EVENTS    - Comma-separated messages of events output by status,
+//	                 in the text format.
+//	FAKE_JSON      - If set, and the runner accepts JSON Lines, status
+//	                 outputs JSON records, including an event and a metric
+//	                 whose name contains '|'.
+//	FAKE_CHANNEL   - If set, and the runner passes a status channel, status
+//	                 is output on it, and a stray update of 'running' on
+//	                 stdout.
+//	FAKE_MALFORMED - If set, status also outputs malformed lines.
+//	FAKE_LINGER    - Duration status sleeps for after outputting status,
+//	                 before exiting.
+//	FAKE_INVALID   - If set, validate finds the benchmark invalid.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		os.Exit(1)
+	}
+	phase := os.Args[1]
+
+	if path := os.Getenv("FAKE_LOG"); path != "" {
+		fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fake: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(fh, phase)
+		fh.Close()
+	}
+
+	for _, pair := range strings.Split(os.Getenv("FAKE_SLEEP"), ",") {
+		if idx := strings.Index(pair, "="); idx > 0 && pair[:idx] == phase {
+			d, _ := time.ParseDuration(pair[idx+1:])
+			time.Sleep(d)
+		}
+	}
+	for _, failed := range strings.Split(os.Getenv("FAKE_FAIL"), ",") {
+		if failed == phase {
+			fmt.Printf("fake %s failed\n", phase)
+			os.Exit(1)
+		}
+	}
+
+	switch phase {
+	case "info":
+		if os.Getenv("FAKE_INFO") == "" {
+			os.Exit(1)
+		}
+		fmt.Println(os.Getenv("FAKE_INFO"))
+	case "status":
+		status()
+	case "validate":
+		fmt.Println("fake validated")
+		if os.Getenv("FAKE_INVALID") != "" {
+			os.Exit(1)
+		}
+	case "setup", "run", "teardown":
+	default:
+		os.Exit(1)
+	}
+}
+
+// status outputs the configured number of updates of 'running', all
+// timestamped with the current time. If classes are given, the updates are
+// output for each class, and if JSON is requested, they are output as JSON
+// records.
+func status() {
+	lines := 3
+	if v := perInvocation("FAKE_LINES"); v != "" {
+		lines, _ = strconv.Atoi(v)
+	}
+	malformed := os.Getenv("FAKE_MALFORMED") != ""
+	series := []string{"running"}
+	if v := os.Getenv("FAKE_LABELS"); v != "" {
+		series = nil
+		for _, class := range strings.Split(v, ",") {
+			series = append(series, "running{class="+class+"}")
+		}
+	}
+
+	out := os.Stdout
+	if fd, err := strconv.Atoi(os.Getenv("SCHEDBENCH_STATUS_FD")); err == nil && os.Getenv("FAKE_CHANNEL") != "" {
+		out = os.NewFile(uintptr(fd), "status")
+		defer out.Close()
+		fmt.Fprintln(out)
+		fmt.Println("stray output running|1000")
+		fmt.Println("running|1000")
+	}
+	if d, err := time.ParseDuration(os.Getenv("FAKE_LINGER")); err == nil {
+		defer time.Sleep(d)
+	}
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	ts := time.Now().UnixNano()
+	if os.Getenv("FAKE_JSON") != "" && os.Getenv("SCHEDBENCH_STATUS_FORMAT") == "json" {
+		fmt.Fprintf(w, `{"message":"all jobs submitted","labels":{"class":"a"},"timestamp":%d}`+"\n", ts)
+		fmt.Fprintf(w, `{"name":"a|b","value":7,"unit":"tasks","timestamp":%q}`+"\n",
+			time.Unix(0, ts).Format(time.RFC3339Nano))
+		for i := 1; i <= lines; i++ {
+			fmt.Fprintf(w, `{"name":"running","value":%d,"timestamp":%d}`+"\n", i, ts)
+		}
+		return
+	}
+	for i := 1; i <= lines; i++ {
+		if malformed {
+			fmt.Fprintln(w, "not a metric")
+			fmt.Fprintf(w, "running|many|%d\n", ts)
+		}
+		for _, name := range series {
+			fmt.Fprintf(w, "%s|%d|%d\n", name, i, ts)
+		}
+	}
+
+	if v := os.Getenv("FAKE_EVENTS"); v != "" {
+		for _, msg := range strings.Split(v, ",") {
+			fmt.Fprintf(w, "!fake|%s|%d\n", msg, ts)
+		}
+	}
+
+	samples, _ := strconv.Atoi(perInvocation("FAKE_SAMPLES"))
+	for i := 1; i <= samples; i++ {
+		fmt.Fprintf(w, "latency|%d||sample\n", i)
+		fmt.Fprintf(w, "started|1|%d|delta\n", ts)
+	}
+}
+
+// perInvocation returns the value of the environment variable for this
+// invocation of status. A comma-separated list gives a value for each
+// invocation in turn, repeating the last once it is exhausted.
+func perInvocation(name string) string {
+	values := strings.Split(os.Getenv(name), ",")
+	if len(values) == 1 {
+		return values[0]
+	}
+	var n int
+	if out, err := ioutil.ReadFile(os.Getenv("FAKE_LOG")); err == nil {
+		n = strings.Count(string(out), "status\n")
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > len(values) {
+		n = len(values)
+	}
+	return values[n-1]
+}
@@ -0,0 +1,100 @@
+// Command plugin is a scripted test implementation served as a Go plugin,
+// used by the end-to-end tests of the runner. It keeps state between steps,
+// which only works if the runner launches it once. How it behaves in each
+// step is controlled by the following environment variables:
+//
+//	FAKE_LOG  - File each invoked step is appended to.
+//	FAKE_FAIL - Comma-separated steps which return an error.
+//	FAKE_HANG - Comma-separated steps which never return, even once
+//	            canceled.
+//	FAKE_JOBS - Number of tasks started by setup, each of which is
+//	            reported as running by status, and as placed in class
+//	            "a", measured in tasks.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/schedbench/plugin"
+)
+
+type benchmark struct {
+	// started is the number of tasks started by setup.
+	started int
+}
+
+func (b *benchmark) Info(ctx context.Context) (*plugin.Info, error) {
+	if err := step("info"); err != nil {
+		return nil, err
+	}
+	return &plugin.Info{Name: "plugin", Version: "1", Metrics: []string{"running"}}, nil
+}
+
+func (b *benchmark) Setup(ctx context.Context) error {
+	if err := step("setup"); err != nil {
+		return err
+	}
+	b.started, _ = strconv.Atoi(os.Getenv("FAKE_JOBS"))
+	return nil
+}
+
+func (b *benchmark) Run(ctx context.Context) error {
+	return step("run")
+}
+
+func (b *benchmark) Status(ctx context.Context, w plugin.StatusWriter) error {
+	if err := step("status"); err != nil {
+		return err
+	}
+	now := time.Now()
+	if err := w.Event("plugin", "tasks started", now); err != nil {
+		return err
+	}
+	for i := 1; i <= b.started; i++ {
+		if err := w.Update("running", float64(i), now); err != nil {
+			return err
+		}
+		placed := &plugin.Metric{Name: "placed", Labels: map[string]string{"class": "a"}, Unit: "tasks"}
+		if err := w.UpdateMetric(placed, float64(i), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *benchmark) Teardown(ctx context.Context) error {
+	return step("teardown")
+}
+
+// step records that the step was invoked, and returns an error if it is
+// scripted to fail.
+func step(phase string) error {
+	if path := os.Getenv("FAKE_LOG"); path != "" {
+		fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(fh, phase)
+		fh.Close()
+	}
+	for _, failed := range strings.Split(os.Getenv("FAKE_FAIL"), ",") {
+		if failed == phase {
+			return fmt.Errorf("plugin %s failed", phase)
+		}
+	}
+	for _, hung := range strings.Split(os.Getenv("FAKE_HANG"), ",") {
+		if hung == phase {
+			select {}
+		}
+	}
+	return nil
+}
+
+func main() {
+	plugin.Serve(&benchmark{})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/hashicorp/schedbench/bench"
+)
+
+// writeTimeline writes the timeline events as CSV to the file at path. The
+// wall-clock time of each event is included, in the same format as the
+// captured output of each step, so that the two can be correlated.
+func writeTimeline(path string, events []bench.TimelineEvent) error {
+	buf := new(bytes.Buffer)
+	csvWriter := csv.NewWriter(buf)
+	csvWriter.Write([]string{"elapsed_ms", "phase", "event", "time"})
+	for _, e := range events {
+		csvWriter.Write([]string{
+			strconv.FormatInt(e.Elapsed, 10),
+			e.Phase,
+			e.Event,
+			e.Time.UTC().Format(bench.LogTimeFormat),
+		})
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed writing CSV data: %v", err)
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
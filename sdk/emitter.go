@@ -0,0 +1,261 @@
+package sdk
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFlushInterval is how often an Emitter flushes buffered updates.
+const DefaultFlushInterval = 100 * time.Millisecond
+
+// Emitter writes status updates in the line format of the status
+// sub-command, `<metric>|<value>|<timestamp>[|<kind>]`, or as JSON Lines.
+// Events are written as `!<name>|<message>|<timestamp>`.
+// Updates are timestamped when they are made, so they are buffered and
+// flushed periodically without any loss of accuracy. It is safe for
+// concurrent use.
+type Emitter struct {
+	w   *bufio.Writer
+	err error
+
+	// json is whether updates are written as JSON Lines, which allows
+	// metric names to contain any characters.
+	json bool
+
+	lock     sync.Mutex
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	doneCh   chan struct{}
+}
+
+// NewEmitter returns an Emitter writing to w, flushing updates every
+// DefaultFlushInterval. It must be closed to flush any remaining updates.
+func NewEmitter(w io.Writer) *Emitter {
+	return NewEmitterInterval(w, DefaultFlushInterval)
+}
+
+// NewEmitterInterval returns an Emitter writing to w, flushing updates at
+// the given interval. An interval of zero flushes each update as it is
+// made.
+func NewEmitterInterval(w io.Writer, interval time.Duration) *Emitter {
+	return newEmitter(w, interval, false)
+}
+
+// NewJSONEmitter returns an Emitter writing JSON Lines to w, flushing
+// updates every DefaultFlushInterval. The runner only accepts JSON Lines if
+// it sets StatusFormatEnv to "json", which Main checks for.
+func NewJSONEmitter(w io.Writer) *Emitter {
+	return newEmitter(w, DefaultFlushInterval, true)
+}
+
+func newEmitter(w io.Writer, interval time.Duration, jsonLines bool) *Emitter {
+	e := &Emitter{
+		w:      bufio.NewWriter(w),
+		json:   jsonLines,
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	if interval > 0 {
+		go e.flushPeriodically(interval)
+	} else {
+		close(e.doneCh)
+	}
+	return e
+}
+
+// Update reports the value of a gauge at the given time, or now if t is
+// zero. Metric names may not contain '|' or line breaks, and may be given
+// labels using Labeled.
+func (e *Emitter) Update(metric string, value float64, t time.Time) error {
+	return e.UpdateKind(metric, KindGauge, value, t)
+}
+
+// UpdateKind reports an update of a metric of the given kind, as with
+// Update.
+func (e *Emitter) UpdateKind(metric string, kind Kind, value float64, t time.Time) error {
+	return e.UpdateMetric(&Metric{Name: metric, Kind: kind}, value, t)
+}
+
+// UpdateMetric reports an update of the series of a metric described by m,
+// as with Update. The unit of the metric is only written as JSON Lines, and
+// otherwise the labels are written as part of its name.
+func (e *Emitter) UpdateMetric(m *Metric, value float64, t time.Time) error {
+	metric, kind := m.Name, m.Kind
+	if !e.json {
+		metric = Labeled(m.Name, m.Labels)
+	}
+	if kind == "" {
+		kind = KindGauge
+	}
+	if m.Name == "" || (!e.json && strings.ContainsAny(metric, "|\r\n")) {
+		return fmt.Errorf("invalid metric name %q", metric)
+	}
+	switch kind {
+	case KindGauge, KindCounter, KindDelta, KindSample:
+	default:
+		return fmt.Errorf("invalid kind %q of metric %q", kind, metric)
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	if e.json {
+		if err := e.writeJSON(m, kind, value, t); err != nil {
+			return err
+		}
+	} else {
+		e.w.WriteString(metric)
+		e.w.WriteByte('|')
+		e.w.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+		e.w.WriteByte('|')
+		e.w.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+		if kind != KindGauge {
+			e.w.WriteByte('|')
+			e.w.WriteString(string(kind))
+		}
+		e.w.WriteByte('\n')
+	}
+	if isClosed(e.doneCh) {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// Event reports something which happened at the given time, or now if t is
+// zero, such as "all jobs submitted". The name may be empty. Unless written
+// as JSON Lines, neither the name nor the message may contain '|' or line
+// breaks.
+func (e *Emitter) Event(name, message string, t time.Time) error {
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("event %q has no message", name)
+	}
+	if !e.json && (strings.ContainsAny(name, "|\r\n") || strings.ContainsAny(message, "|\r\n")) {
+		return fmt.Errorf("invalid event %q: %q", name, message)
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.err != nil {
+		return e.err
+	}
+	if e.json {
+		out, err := json.Marshal(&jsonEvent{Name: name, Message: message, Timestamp: t.UnixNano()})
+		if err != nil {
+			return fmt.Errorf("invalid event %q: %v", name, err)
+		}
+		e.w.Write(out)
+		e.w.WriteByte('\n')
+	} else {
+		e.w.WriteByte('!')
+		e.w.WriteString(name)
+		e.w.WriteByte('|')
+		e.w.WriteString(message)
+		e.w.WriteByte('|')
+		e.w.WriteString(strconv.FormatInt(t.UnixNano(), 10))
+		e.w.WriteByte('\n')
+	}
+	if isClosed(e.doneCh) {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// jsonUpdate is an update written as a line of JSON.
+type jsonUpdate struct {
+	Name      string  `json:"name"`
+	Value     float64 `json:"value"`
+	Timestamp int64   `json:"timestamp"`
+	Kind      Kind    `json:"kind,omitempty"`
+	Labels    Labels  `json:"labels,omitempty"`
+	Unit      string  `json:"unit,omitempty"`
+}
+
+// jsonEvent is an event written as a line of JSON.
+type jsonEvent struct {
+	Name      string `json:"name,omitempty"`
+	Message   string `json:"message"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// writeJSON writes an update as a line of JSON. The lock must be held.
+func (e *Emitter) writeJSON(m *Metric, kind Kind, value float64, t time.Time) error {
+	u := &jsonUpdate{
+		Name:      m.Name,
+		Value:     value,
+		Timestamp: t.UnixNano(),
+		Labels:    m.Labels,
+		Unit:      m.Unit,
+	}
+	if kind != KindGauge {
+		u.Kind = kind
+	}
+	out, err := json.Marshal(u)
+	if err != nil {
+		return fmt.Errorf("invalid update of metric %q: %v", m.Name, err)
+	}
+	e.w.Write(out)
+	e.w.WriteByte('\n')
+	return nil
+}
+
+// Running reports the number of tasks in the running state at the given
+// time, or now if t is zero.
+func (e *Emitter) Running(n int, t time.Time) error {
+	return e.Update("running", float64(n), t)
+}
+
+// Flush writes any buffered updates.
+func (e *Emitter) Flush() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// Close stops flushing periodically and writes any buffered updates.
+// Updates made after Close are written immediately.
+func (e *Emitter) Close() error {
+	e.stopOnce.Do(func() { close(e.stopCh) })
+	<-e.doneCh
+	return e.Flush()
+}
+
+func (e *Emitter) flushPeriodically(interval time.Duration) {
+	defer close(e.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.Flush()
+		case <-e.stopCh:
+			return
+		}
+	}
+}
+
+// isClosed returns whether the channel has been closed.
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RequireEnv returns an error naming any of the environment variables which
+// are unset or empty.
+func RequireEnv(names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%s must be provided", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// EnvString returns the value of the environment variable, or an error if
+// it is unset or empty.
+func EnvString(name string) (string, error) {
+	if err := RequireEnv(name); err != nil {
+		return "", err
+	}
+	return os.Getenv(name), nil
+}
+
+// EnvInt returns the value of the environment variable as an integer, or an
+// error if it is unset or not numeric.
+func EnvInt(name string) (int, error) {
+	v, err := EnvString(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be numeric", name)
+	}
+	return i, nil
+}
+
+// EnvDuration returns the value of the environment variable as a Go
+// duration such as "90s", or def if it is unset.
+func EnvDuration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration: %v", name, err)
+	}
+	return d, nil
+}
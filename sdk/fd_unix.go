@@ -0,0 +1,13 @@
+//go:build !windows
+// +build !windows
+
+package sdk
+
+import "syscall"
+
+// closeOnExec marks the file descriptor to be closed in any program the
+// implementation executes, so that processes it starts, which may outlive
+// it, do not inherit the status channel and keep it open.
+func closeOnExec(fd int) {
+	syscall.CloseOnExec(fd)
+}
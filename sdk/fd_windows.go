@@ -0,0 +1,8 @@
+//go:build windows
+// +build windows
+
+package sdk
+
+// closeOnExec is a no-op on Windows, where the runner passes no status
+// channel.
+func closeOnExec(fd int) {}
@@ -0,0 +1,32 @@
+package sdk
+
+import (
+	"sort"
+	"strings"
+)
+
+// Labels holds the dimensions of a metric, such as the node class tasks are
+// running on. Names and values may not contain any of "{}=,|" or line
+// breaks.
+type Labels map[string]string
+
+// Labeled returns the name of the series of a metric with the given labels,
+// to be passed to Update, such as "running{class=class_1,node=abc}". The
+// runner can then filter and group the series of the metric by label.
+// Labels are sorted by name, so that each series has a single name.
+func Labeled(metric string, labels Labels) string {
+	if len(labels) == 0 {
+		return metric
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + labels[name]
+	}
+	return metric + "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,154 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+)
+
+// defaultHelp is printed if the executable is run directly, and the
+// Benchmark does not implement Helper.
+const defaultHelp = `
+NOTICE: This is a benchmark implementation binary and is not intended to be
+run directly. The full path to this binary should be passed to bench-runner.
+`
+
+// Main executes the sub-command the executable was invoked with, and exits
+// with its exit code. It should be called from the main function of the
+// test implementation.
+//
+// The context passed to each step is canceled when the process receives
+// SIGINT or SIGTERM, as sent by the runner when a step times out or the
+// runner is interrupted.
+func Main(b Benchmark) {
+	// Log everything to stderr so the runner can pipe it through
+	log.SetOutput(os.Stderr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigCh
+		log.Printf("[DEBUG] sdk: received %v; stopping", sig)
+		cancel()
+	}()
+
+	code := dispatch(ctx, b, os.Args[1:], os.Stdout)
+	signal.Stop(sigCh)
+	cancel()
+	os.Exit(code)
+}
+
+// dispatch executes the sub-command given by args, writing its output to
+// stdout. Returns the exit code of the sub-command.
+func dispatch(ctx context.Context, b Benchmark, args []string, stdout io.Writer) int {
+	if len(args) != 1 {
+		help := defaultHelp
+		if h, ok := b.(Helper); ok {
+			help = h.Help()
+		}
+		fmt.Fprint(os.Stderr, help)
+		return 1
+	}
+	phase := args[0]
+
+	// Describing the implementation must work without any configuration
+	if phase != "info" {
+		if c, ok := b.(Configurer); ok {
+			if err := c.Configure(); err != nil {
+				log.Printf("[ERR] sdk: %v", err)
+				return 1
+			}
+		}
+	}
+
+	var err error
+	switch phase {
+	case "info":
+		err = info(ctx, b, stdout)
+	case "setup":
+		err = b.Setup(ctx)
+	case "run":
+		err = b.Run(ctx)
+	case "status":
+		err = status(ctx, b, stdout)
+	case "validate":
+		err = validate(ctx, b, stdout)
+	case "teardown":
+		err = b.Teardown(ctx)
+	default:
+		log.Printf("[ERR] sdk: unknown sub-command %q", phase)
+		return 1
+	}
+	if err != nil {
+		log.Printf("[ERR] sdk: %s failed: %v", phase, err)
+		return 1
+	}
+	return 0
+}
+
+// info writes the info of the benchmark to w as JSON.
+func info(ctx context.Context, b Benchmark, w io.Writer) error {
+	d, ok := b.(Describer)
+	if !ok {
+		return fmt.Errorf("not supported by the implementation")
+	}
+	info, err := d.Info(ctx)
+	if err != nil {
+		return err
+	}
+	_, info.Validate = b.(Validator)
+	return json.NewEncoder(w).Encode(info)
+}
+
+// status reports the status updates of the benchmark, writing them to the
+// status channel passed by the runner, or to stdout if there is none.
+func status(ctx context.Context, b Benchmark, stdout io.Writer) error {
+	w := stdout
+	if v := os.Getenv(StatusFDEnv); v != "" {
+		fd, err := strconv.Atoi(v)
+		if err != nil || fd < 0 {
+			return fmt.Errorf("invalid status channel %q in %s", v, StatusFDEnv)
+		}
+		closeOnExec(fd)
+		f := os.NewFile(uintptr(fd), "status")
+		defer f.Close()
+
+		// Claim the channel straight away, so that the runner ignores
+		// anything printed to stdout from now on.
+		if _, err := f.Write([]byte("\n")); err != nil {
+			return fmt.Errorf("failed writing to status channel: %v", err)
+		}
+		w = f
+	}
+
+	e := NewEmitter(w)
+	if os.Getenv(StatusFormatEnv) == "json" {
+		e = NewJSONEmitter(w)
+	}
+	err := b.Status(ctx, e)
+	if cerr := e.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// validate writes the output of validating the benchmark to w, returning an
+// error if it is invalid.
+func validate(ctx context.Context, b Benchmark, w io.Writer) error {
+	v, ok := b.(Validator)
+	if !ok {
+		return fmt.Errorf("not supported by the implementation")
+	}
+	out, err := v.Validate(ctx)
+	if _, werr := io.WriteString(w, out); werr != nil && err == nil {
+		err = werr
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+// Package sdk helps write test implementations in Go. An implementation
+// satisfies the Benchmark interface and calls Main from its main function,
+// which dispatches the sub-command the runner invoked it with:
+//
+//	func main() {
+//		sdk.Main(&myBenchmark{})
+//	}
+//
+// Status updates are written using an Emitter, which handles the formatting,
+// timestamping and buffering of the status output.
+package sdk
+
+import (
+	"context"
+	"time"
+)
+
+// Benchmark is implemented by test implementations. Each method corresponds
+// to a sub-command of the benchmark, and should return once the context is
+// canceled, which happens if the runner asks the step to stop.
+type Benchmark interface {
+	// Setup performs any work required before the benchmark starts.
+	Setup(ctx context.Context) error
+
+	// Run instructs the scheduler to begin work, returning once all work
+	// has been submitted.
+	Run(ctx context.Context) error
+
+	// Status reports updates of the metrics of the benchmark to w, and
+	// returns once all work has completed. It is called shortly before Run.
+	Status(ctx context.Context, w StatusWriter) error
+
+	// Teardown cleans up after the benchmark. It is called even if a
+	// previous step failed.
+	Teardown(ctx context.Context) error
+}
+
+// StatusFormatEnv is the environment variable in which the runner names the
+// richest format of status output it accepts. If it is "json", Main writes
+// status updates as JSON Lines, and otherwise in the text format.
+const StatusFormatEnv = "SCHEDBENCH_STATUS_FORMAT"
+
+// StatusFDEnv is the environment variable in which the runner passes the
+// number of a file descriptor for status to be output on, so that it is
+// kept apart from anything else printed to stdout. If it is set, Main
+// writes status updates to it instead of stdout, and marks it close-on-exec
+// so that processes started by the implementation do not inherit it.
+const StatusFDEnv = "SCHEDBENCH_STATUS_FD"
+
+// StatusWriter receives the status updates of a benchmark.
+type StatusWriter interface {
+	// Update reports the value of a gauge at the given time. A zero time
+	// means the update happened when it is reported.
+	Update(metric string, value float64, t time.Time) error
+
+	// UpdateKind reports an update of a metric of the given kind, as with
+	// Update.
+	UpdateKind(metric string, kind Kind, value float64, t time.Time) error
+
+	// UpdateMetric reports an update of the series of a metric described by
+	// m, as with Update, along with its labels and unit.
+	UpdateMetric(m *Metric, value float64, t time.Time) error
+
+	// Event reports something which happened at the given time, such as
+	// "all jobs submitted", to annotate the timeline of the benchmark. The
+	// name optionally categorizes the event.
+	Event(name, message string, t time.Time) error
+}
+
+// Metric describes the series of a metric an update is reported for.
+type Metric struct {
+	Name string
+
+	// Kind is the kind of the metric, which defaults to KindGauge.
+	Kind Kind
+
+	// Labels are the dimensions of the series, as with Labeled.
+	Labels Labels
+
+	// Unit is the unit the metric is measured in, such as "ms". It is only
+	// passed to the runner in the JSON Lines format.
+	Unit string
+}
+
+// Kind is the kind of a metric, which determines how the runner aggregates
+// its updates.
+type Kind string
+
+const (
+	// KindGauge is a value which may rise or fall, such as the number of
+	// running tasks. Each update replaces the previous value.
+	KindGauge Kind = "gauge"
+
+	// KindCounter is a cumulative count which only rises. A decrease is
+	// treated as the counter having been reset.
+	KindCounter Kind = "counter"
+
+	// KindDelta is an increment of a count, such as 1 for each task which
+	// starts. The runner sums the increments.
+	KindDelta Kind = "delta"
+
+	// KindSample is a single observation of a distribution, such as the
+	// time a task took to start. The runner derives percentiles of the
+	// samples.
+	KindSample Kind = "sample"
+)
+
+// Info describes a test implementation, as output by the info sub-command.
+type Info struct {
+	Name             string   `json:"name"`
+	Version          string   `json:"version"`
+	SchedulerVersion string   `json:"scheduler_version,omitempty"`
+	RequiredEnv      []string `json:"required_env,omitempty"`
+	Metrics          []string `json:"metrics,omitempty"`
+	ExpectedTasks    int      `json:"expected_tasks,omitempty"`
+
+	// Validate is set by Main, according to whether the Benchmark
+	// implements Validator.
+	Validate bool `json:"validate,omitempty"`
+}
+
+// Describer may optionally be implemented by a Benchmark to describe
+// itself, as with the info sub-command.
+type Describer interface {
+	Info(ctx context.Context) (*Info, error)
+}
+
+// Validator may optionally be implemented by a Benchmark to check its
+// correctness once status completes, as with the validate sub-command. The
+// output is recorded with the results. If an error is returned, the
+// benchmark is considered invalid.
+type Validator interface {
+	Validate(ctx context.Context) (string, error)
+}
+
+// Configurer may optionally be implemented by a Benchmark to load its
+// configuration, typically from the environment, before any step other
+// than info is executed. If an error is returned, the step fails.
+type Configurer interface {
+	Configure() error
+}
+
+// Helper may optionally be implemented by a Benchmark to describe how it
+// is used, which is printed if the executable is run directly.
+type Helper interface {
+	Help() string
+}
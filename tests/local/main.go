@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/schedbench/sdk"
+)
+
+const (
+	// version is the version of this benchmark implementation.
+	version = "0.1.0"
+
+	// defaultCommand is the command each task runs if none is given. It
+	// runs until the task is killed by teardown.
+	defaultCommand = "sleep 3600"
+
+	// pollInterval is how often the status step checks for tasks which
+	// have started.
+	pollInterval = 50 * time.Millisecond
+)
+
+func main() {
+	sdk.Main(&benchmark{})
+}
+
+// benchmark schedules each task as a plain local process, as a baseline for
+// how quickly tasks could possibly be started on a single machine. The run
+// step starts the processes, recording when each started in the state
+// directory, where the status step, executing in another process, picks
+// them up. The processes outlive the run step, and are killed by teardown.
+type benchmark struct {
+	// tasks is the number of processes to start, each running command.
+	tasks   int
+	command []string
+
+	// concurrency is the maximum number of processes being started at once.
+	concurrency int
+
+	// stateDir is the directory the events and process IDs of the tasks
+	// are recorded in.
+	stateDir string
+}
+
+func (b *benchmark) Help() string {
+	return usage
+}
+
+func (b *benchmark) Configure() error {
+	var err error
+	if b.tasks, err = sdk.EnvInt("TASKS"); err != nil {
+		return err
+	}
+	if b.tasks < 0 {
+		return fmt.Errorf("TASKS must not be negative")
+	}
+
+	b.concurrency = runtime.NumCPU()
+	if os.Getenv("CONCURRENCY") != "" {
+		if b.concurrency, err = sdk.EnvInt("CONCURRENCY"); err != nil {
+			return err
+		}
+		if b.concurrency < 1 {
+			return fmt.Errorf("CONCURRENCY must be at least 1")
+		}
+	}
+
+	command := os.Getenv("COMMAND")
+	if command == "" {
+		command = defaultCommand
+	}
+	if b.command = strings.Fields(command); len(b.command) == 0 {
+		return fmt.Errorf("COMMAND must not be blank")
+	}
+
+	// The state directory is required rather than defaulted, since a fixed
+	// default would be shared by benchmarks running at the same time.
+	if b.stateDir = os.Getenv("STATE_DIR"); b.stateDir == "" {
+		return fmt.Errorf("STATE_DIR must be set")
+	}
+	return nil
+}
+
+func (b *benchmark) Info(ctx context.Context) (*sdk.Info, error) {
+	out := &sdk.Info{
+		Name:             "local",
+		Version:          version,
+		SchedulerVersion: runtime.GOOS + "/" + runtime.GOARCH,
+		RequiredEnv:      []string{"TASKS", "STATE_DIR"},
+		Metrics:          []string{"running", "failed"},
+	}
+	if tasks, err := strconv.Atoi(os.Getenv("TASKS")); err == nil {
+		out.ExpectedTasks = tasks
+	}
+	return out, nil
+}
+
+// eventsPath returns the path of the file recording when each task started
+// or failed to start, one per line, such as "running <unix ns>".
+func (b *benchmark) eventsPath() string {
+	return filepath.Join(b.stateDir, "events.log")
+}
+
+// pidsPath returns the path of the file recording the process ID of each
+// task along with its command line, one per line, such as "123 sleep 3600".
+func (b *benchmark) pidsPath() string {
+	return filepath.Join(b.stateDir, "pids")
+}
+
+// runPath returns the path of the file recording the process ID of the run
+// step while it executes, which it replaces with "exited" once it returns.
+func (b *benchmark) runPath() string {
+	return filepath.Join(b.stateDir, "run")
+}
+
+// runExited returns whether the run step has exited, either having recorded
+// that it returned, or by its process no longer running. It has not exited
+// if it has not started.
+func (b *benchmark) runExited() (bool, error) {
+	out, err := ioutil.ReadFile(b.runPath())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed reading run file: %v", err)
+	}
+	content := strings.TrimSpace(string(out))
+	if content == "exited" {
+		return true, nil
+	}
+	pid, err := strconv.Atoi(content)
+	if err != nil {
+		// The file may have been read while being written
+		return false, nil
+	}
+	commands, err := processCommands()
+	if err != nil {
+		return false, err
+	}
+	_, ok := commands[pid]
+	return !ok, nil
+}
+
+func (b *benchmark) Setup(ctx context.Context) error {
+	if err := os.MkdirAll(b.stateDir, 0755); err != nil {
+		return fmt.Errorf("failed creating state directory: %v", err)
+	}
+	if err := os.Remove(b.runPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed removing run file: %v", err)
+	}
+
+	// Clean up after any previous benchmark which was not torn down, so
+	// that status only sees the tasks of this one.
+	return b.killTasks()
+}
+
+func (b *benchmark) Run(ctx context.Context) error {
+	// Record the process of the step, so that status stops waiting for
+	// tasks once it exits
+	if err := ioutil.WriteFile(b.runPath(), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return fmt.Errorf("failed writing run file: %v", err)
+	}
+	defer ioutil.WriteFile(b.runPath(), []byte("exited"), 0644)
+
+	events, err := os.OpenFile(b.eventsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed opening events file: %v", err)
+	}
+	defer events.Close()
+	pids, err := os.OpenFile(b.pidsPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed opening pids file: %v", err)
+	}
+	defer pids.Close()
+
+	concurrency := b.concurrency
+	if b.tasks < concurrency {
+		concurrency = b.tasks
+	}
+	log.Printf("[DEBUG] local: starting %d tasks using %d parallel starters", b.tasks, concurrency)
+
+	// Start the tasks, recording each as it starts
+	var lock sync.Mutex
+	record := func(event string, pid int) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if pid != 0 {
+			if _, err := fmt.Fprintf(pids, "%d %s\n", pid, strings.Join(b.command, " ")); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(events, "%s %d\n", event, time.Now().UnixNano())
+		return err
+	}
+
+	indexCh := make(chan int)
+	errCh := make(chan error, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexCh {
+				if err := b.startTask(index, record); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}()
+	}
+
+SUBMIT:
+	for i := 0; i < b.tasks; i++ {
+		select {
+		case indexCh <- i:
+		case err = <-errCh:
+			break SUBMIT
+		case <-ctx.Done():
+			err = ctx.Err()
+			break SUBMIT
+		}
+	}
+	close(indexCh)
+	wg.Wait()
+	if err == nil {
+		select {
+		case err = <-errCh:
+		default:
+		}
+	}
+	return err
+}
+
+// startTask starts the process of a single task, recording whether it
+// started. A task which fails to start is recorded as failed, and an error
+// is only returned if the outcome could not be recorded.
+func (b *benchmark) startTask(index int, record func(event string, pid int) error) error {
+	cmd := exec.Command(b.command[0], b.command[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("TASK_INDEX=%d", index))
+
+	// The process must not inherit the output of the run step, which the
+	// runner waits to be closed before the step completes.
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	if err := cmd.Start(); err != nil {
+		log.Printf("[ERR] local: failed starting task %d: %v", index, err)
+		return record("failed", 0)
+	}
+	if err := record("running", cmd.Process.Pid); err != nil {
+		return fmt.Errorf("failed recording task %d: %v", index, err)
+	}
+
+	// Release the process, which outlives the run step
+	return cmd.Process.Release()
+}
+
+// Status reports the tasks as they are recorded by the run step, until
+// every task is recorded. If the run step exits before then, having failed
+// or been killed, status reports whatever it recorded and returns an error.
+func (b *benchmark) Status(ctx context.Context, w sdk.StatusWriter) error {
+	var offset int64
+	var partial []byte
+	var running, failed int
+	var exited bool
+	for running+failed < b.tasks {
+		if exited {
+			return fmt.Errorf("run exited having recorded %d of %d tasks", running+failed, b.tasks)
+		}
+		if err := sleep(ctx, pollInterval); err != nil {
+			return err
+		}
+
+		// Read whatever has been recorded since the last poll. If nothing
+		// was, check whether run has exited, and read once more in case it
+		// recorded anything since, before giving up on it.
+		out, err := readFrom(b.eventsPath(), offset)
+		if err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			if exited, err = b.runExited(); err != nil {
+				return err
+			}
+			if exited {
+				if out, err = readFrom(b.eventsPath(), offset); err != nil {
+					return err
+				}
+			}
+		}
+		offset += int64(len(out))
+		lines := bytes.Split(append(partial, out...), []byte("\n"))
+		partial = lines[len(lines)-1]
+
+		for _, line := range lines[:len(lines)-1] {
+			parts := strings.Fields(string(line))
+			if len(parts) != 2 {
+				return fmt.Errorf("invalid event %q", line)
+			}
+			ts, err := strconv.ParseInt(parts[1], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid event %q: %v", line, err)
+			}
+
+			metric, value := "running", &running
+			if parts[0] == "failed" {
+				metric, value = "failed", &failed
+			}
+			*value++
+			if err := w.Update(metric, float64(*value), time.Unix(0, ts)); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("[DEBUG] local: %d tasks running, %d failed", running, failed)
+	return nil
+}
+
+// readFrom reads the file from the offset to its end. A file which does not
+// exist yet is treated as empty.
+func readFrom(path string, offset int64) ([]byte, error) {
+	fh, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed opening events file: %v", err)
+	}
+	defer fh.Close()
+	if _, err := fh.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed reading events file: %v", err)
+	}
+	out, err := ioutil.ReadAll(fh)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading events file: %v", err)
+	}
+	return out, nil
+}
+
+// Teardown kills the tasks and removes the record of them, so that the
+// setup of the next benchmark has nothing left to clean up.
+func (b *benchmark) Teardown(ctx context.Context) error {
+	return b.killTasks()
+}
+
+// killTasks kills the process of every task which was started, and removes
+// the record of the tasks. The tasks may have been recorded long ago, such
+// as by a benchmark which was not torn down before a reboot, so a process is
+// only killed if it is still running the command of the task, rather than
+// having reused its process ID.
+func (b *benchmark) killTasks() error {
+	out, err := ioutil.ReadFile(b.pidsPath())
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed reading pids file: %v", err)
+	}
+
+	var killed int
+	var commands map[int]string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		pid, err := strconv.Atoi(parts[0])
+		if err != nil || len(parts) != 2 {
+			return fmt.Errorf("invalid task %q", line)
+		}
+		if commands == nil {
+			if commands, err = processCommands(); err != nil {
+				return err
+			}
+		}
+		if commands[pid] != parts[1] {
+			continue
+		}
+		proc, err := os.FindProcess(pid)
+		if err != nil {
+			continue
+		}
+		if proc.Kill() == nil {
+			killed++
+		}
+	}
+	if killed != 0 {
+		log.Printf("[DEBUG] local: killed %d tasks", killed)
+	}
+
+	for _, path := range []string{b.pidsPath(), b.eventsPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed removing %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// processCommands returns the command line of every running process, keyed
+// by its process ID.
+func processCommands() (map[int]string, error) {
+	out, err := exec.Command("ps", "-e", "-o", "pid=,args=").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed listing processes: %v", err)
+	}
+	commands := make(map[int]string)
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if pid, err := strconv.Atoi(parts[0]); err == nil && len(parts) == 2 {
+			commands[pid] = strings.TrimSpace(parts[1])
+		}
+	}
+	return commands, nil
+}
+
+// sleep waits for the duration, returning early with an error if the
+// context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const usage = `
+NOTICE: This is a benchmark implementation binary and is not intended to be
+run directly. The full path to this binary should be passed to bench-runner.
+
+This benchmark measures the time taken to start tasks as plain processes on
+the local machine, without any scheduler. It gives a floor against which
+the results of schedulers, such as Nomad's raw_exec driver, can be compared.
+
+To run this benchmark, run the benchmark runner utility, passing this
+executable as the first argument. The following environment variables are
+used:
+
+  TASKS       - The number of tasks to start. Required.
+  CONCURRENCY - The maximum number of tasks being started at once. Defaults
+                to the number of CPUs.
+  COMMAND     - The command each task runs, split on whitespace. Defaults to
+                "sleep 3600". Each task is killed by teardown, and has its
+                index in the TASK_INDEX environment variable.
+  STATE_DIR   - The directory the tasks are recorded in. Required, and must
+                not be shared with any other benchmark running at the
+                same time.
+
+An example use would look like this:
+
+  $ STATE_DIR=$(mktemp -d) TASKS=1000 CONCURRENCY=16 bench-runner bench-local
+`
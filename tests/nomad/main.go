@@ -2,18 +2,20 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
 	"log"
 	"os"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/garyburd/redigo/redis"
 	"github.com/hashicorp/nomad/api"
 	"github.com/hashicorp/nomad/jobspec"
 	"github.com/hashicorp/nomad/nomad/structs"
+	"github.com/hashicorp/schedbench/sdk"
 	"github.com/mitchellh/copystructure"
 )
 
@@ -32,68 +34,179 @@ const (
 	pendingAllocTries = 3
 )
 
-var numJobs, totalProcs int
-var jobFile string
-
 func main() {
-	// Log everything to stderr so the runner can pipe it through
-	log.SetOutput(os.Stderr)
+	sdk.Main(&benchmark{})
+}
 
-	// Check the args
-	if len(os.Args) != 2 {
-		log.Fatalln(usage)
-	}
+// benchmark implements the benchmark using Nomad.
+type benchmark struct {
+	// numJobs is the number of times to submit the job in jobFile.
+	numJobs int
+	jobFile string
+}
 
-	// Get the number of jobs to submit
+func (b *benchmark) Help() string {
+	return usage
+}
+
+func (b *benchmark) Configure() error {
 	var err error
-	v := os.Getenv("JOBS")
-	if numJobs, err = strconv.Atoi(v); err != nil {
-		log.Fatalln("[ERR] nomad: JOBS must be numeric")
+	if b.numJobs, err = sdk.EnvInt("JOBS"); err != nil {
+		return err
+	}
+	if b.jobFile, err = sdk.EnvString("JOBSPEC"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// version is the version of this benchmark implementation.
+const version = "0.1.0"
+
+func (b *benchmark) Info(ctx context.Context) (*sdk.Info, error) {
+	out := &sdk.Info{
+		Name:        "nomad",
+		Version:     version,
+		RequiredEnv: []string{"JOBS", "JOBSPEC"},
+		Metrics: []string{
+			"running", "received", "failed_evals", "failed_allocs",
+			"placed_run", "placed_failed", "placed_stop", "evals", "flips",
+		},
+	}
+
+	// Ask the agent for the version of Nomad, if it is reachable
+	if client, err := api.NewClient(api.DefaultConfig()); err == nil {
+		if self, err := client.Agent().Self(); err == nil {
+			if v, ok := self["config"]["Version"].(string); ok {
+				out.SchedulerVersion = v
+			}
+		}
+	}
+
+	// Info is called without the benchmark having been configured, since
+	// describing it must work without any configuration. The expected
+	// number of tasks is only given if it can be configured.
+	if err := b.Configure(); err == nil {
+		if job, err := jobspec.ParseFile(b.jobFile); err == nil {
+			out.ExpectedTasks = expectedAllocs(job) * b.numJobs
+		}
+	}
+	return out, nil
+}
+
+// expectedAllocs returns the number of allocations a single copy of the job
+// is expected to create.
+func expectedAllocs(job *structs.Job) int {
+	var total int
+	for _, group := range job.TaskGroups {
+		total += (group.Count * len(group.Tasks))
 	}
+	return total
+}
 
-	// Get the location of the job file
-	if jobFile = os.Getenv("JOBSPEC"); jobFile == "" {
-		log.Fatalln("[ERR] nomad: JOBSPEC must be provided")
+func (b *benchmark) Setup(ctx context.Context) error {
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		// No setup required
+		return nil
 	}
 
-	// Switch on the command
-	switch os.Args[1] {
-	case "setup":
-		os.Exit(handleSetup())
-	case "run":
-		os.Exit(handleRun())
-	case "status":
-		os.Exit(handleStatus())
-	case "teardown":
-		os.Exit(handleTeardown())
-	default:
-		log.Fatalf("unknown command: %q", os.Args[1])
+	// Reset the counters of the classlogger so only this run is validated
+	job, err := jobspec.ParseFile(b.jobFile)
+	if err != nil {
+		return fmt.Errorf("failed parsing job file: %v", err)
 	}
+	conn, err := redis.Dial("tcp", redisAddr)
+	if err != nil {
+		return fmt.Errorf("failed connecting to redis: %v", err)
+	}
+	defer conn.Close()
+	for class := range expectedClasses(job, b.numJobs) {
+		if _, err := conn.Do("DEL", class); err != nil {
+			return fmt.Errorf("failed resetting count of class %q: %v", class, err)
+		}
+	}
+	return nil
 }
 
-func handleSetup() int {
-	// No setup required
-	return 0
+// expectedClasses returns the number of tasks expected to run on each node
+// class when the job is submitted numJobs times, for task groups constrained
+// to a single node class.
+func expectedClasses(job *structs.Job, numJobs int) map[string]int {
+	classes := make(map[string]int)
+	for _, group := range job.TaskGroups {
+		for _, c := range group.Constraints {
+			if c.LTarget == "${node.class}" && c.Operand == "=" {
+				classes[c.RTarget] += group.Count * len(group.Tasks) * numJobs
+			}
+		}
+	}
+	return classes
 }
 
-func handleRun() int {
+func (b *benchmark) Validate(ctx context.Context) (string, error) {
+	redisAddr := os.Getenv("REDIS_ADDR")
+	if redisAddr == "" {
+		return "validation skipped: REDIS_ADDR is not set\n", nil
+	}
+	job, err := jobspec.ParseFile(b.jobFile)
+	if err != nil {
+		return "", fmt.Errorf("failed parsing job file: %v", err)
+	}
+	conn, err := redis.Dial("tcp", redisAddr)
+	if err != nil {
+		return "", fmt.Errorf("failed connecting to redis: %v", err)
+	}
+	defer conn.Close()
+
+	// Compare the number of tasks the classlogger saw start on each node
+	// class with the number the job placed there. Restarted tasks are
+	// counted more than once, so only a shortfall is a failure.
+	classes := expectedClasses(job, b.numJobs)
+	names := make([]string, 0, len(classes))
+	for class := range classes {
+		names = append(names, class)
+	}
+	sort.Strings(names)
+
+	var out bytes.Buffer
+	valid := true
+	for _, class := range names {
+		count, err := redis.Int(conn.Do("GET", class))
+		if err != nil && err != redis.ErrNil {
+			return out.String(), fmt.Errorf("failed reading count of class %q: %v", class, err)
+		}
+		fmt.Fprintf(&out, "%s: %d of %d tasks started\n", class, count, classes[class])
+		if count < classes[class] {
+			valid = false
+		}
+	}
+	if !valid {
+		return out.String(), fmt.Errorf("tasks did not start on their expected node classes")
+	}
+	return out.String(), nil
+}
+
+func (b *benchmark) Run(ctx context.Context) error {
+	numJobs := b.numJobs
+
 	// Parse the job file
-	job, err := jobspec.ParseFile(jobFile)
+	job, err := jobspec.ParseFile(b.jobFile)
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed parsing job file: %v", err)
+		return fmt.Errorf("failed parsing job file: %v", err)
 	}
 
 	// Convert to an API struct for submission
 	apiJob, err := convertStructJob(job)
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed converting job: %v", err)
+		return fmt.Errorf("failed converting job: %v", err)
 	}
 	jobID := apiJob.ID
 
 	// Get the API client
 	client, err := api.NewClient(api.DefaultConfig())
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed creating nomad client: %v", err)
+		return fmt.Errorf("failed creating nomad client: %v", err)
 	}
 	jobs := client.Jobs()
 
@@ -117,14 +230,18 @@ func handleRun() int {
 	for i := 0; i < numJobs; i++ {
 		copy, err := copystructure.Copy(apiJob)
 		if err != nil {
-			log.Fatalf("[ERR] nomad: failed to copy api job: %v", err)
+			return fmt.Errorf("failed to copy api job: %v", err)
 		}
 
 		// Increment the job ID
 		jobCopy := copy.(*api.Job)
 		jobCopy.ID = fmt.Sprintf("%s-%d", jobID, i)
 		submitting[jobCopy.ID] = jobCopy
-		jobsCh <- jobCopy
+		select {
+		case jobsCh <- jobCopy:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	// Collect errors if any
@@ -132,17 +249,17 @@ func handleRun() int {
 		select {
 		case err := <-errCh:
 			if err != nil {
-				log.Fatalf("[ERR] nomad: failed submitting job: %v", err)
+				return fmt.Errorf("failed submitting job: %v", err)
 			}
-		case <-stopCh:
-			return 0
+		case <-ctx.Done():
+			return ctx.Err()
 		}
 	}
 
 	// Get the jobs were submitted.
 	submitted, _, err := jobs.List(nil)
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed listing jobs: %v", err)
+		return fmt.Errorf("failed listing jobs: %v", err)
 	}
 
 	// See if anything didn't get registered
@@ -159,7 +276,7 @@ func handleRun() int {
 		}
 	}
 
-	return 0
+	return nil
 }
 
 func submitJobs(client *api.Jobs, jobs <-chan *api.Job, stopCh chan struct{}, errCh chan<- error) {
@@ -174,17 +291,15 @@ func submitJobs(client *api.Jobs, jobs <-chan *api.Job, stopCh chan struct{}, er
 	}
 }
 
-func handleStatus() int {
+func (b *benchmark) Status(ctx context.Context, w sdk.StatusWriter) error {
+	numJobs := b.numJobs
+
 	// Parse the job file to get the total expected allocs
-	job, err := jobspec.ParseFile(jobFile)
+	job, err := jobspec.ParseFile(b.jobFile)
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed parsing job file: %v", err)
-	}
-	var totalAllocs int
-	for _, group := range job.TaskGroups {
-		totalAllocs += (group.Count * len(group.Tasks))
+		return fmt.Errorf("failed parsing job file: %v", err)
 	}
-	totalAllocs *= numJobs
+	totalAllocs := expectedAllocs(job) * numJobs
 	minEvals := numJobs
 	log.Printf("[DEBUG] nomad: expecting %d allocs (%d evals minimum)", totalAllocs, minEvals)
 
@@ -198,7 +313,7 @@ func handleStatus() int {
 	// Get the API client
 	client, err := api.NewClient(api.DefaultConfig())
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed creating nomad client: %v", err)
+		return fmt.Errorf("failed creating nomad client: %v", err)
 	}
 	evalEndpoint := client.Evaluations()
 
@@ -217,7 +332,9 @@ EVAL_POLL:
 		waitTime, exceeded := getSleepTime(cutoff)
 		if !exceeded {
 			log.Printf("[DEBUG] nomad: next eval poll in %s", waitTime)
-			time.Sleep(waitTime)
+			if err := sleep(ctx, waitTime); err != nil {
+				return err
+			}
 		}
 
 		// Start the query
@@ -261,6 +378,10 @@ EVAL_POLL:
 					needPoll = true
 				} else if tries == blockedEvalTries {
 					log.Printf("[DEBUG] nomad: abandoning blocked eval %q", eval.ID)
+					msg := fmt.Sprintf("eval %s blocked", eval.ID)
+					if err := w.Event("blocked_eval", msg, time.Time{}); err != nil {
+						return err
+					}
 				}
 			case "pending":
 				needPoll = true
@@ -274,6 +395,13 @@ EVAL_POLL:
 		break
 	}
 
+	// Mark when the evals were seen through the scheduler, which is only
+	// accurate to within the poll interval.
+	msg := fmt.Sprintf("%d evals complete", len(evals))
+	if err := w.Event("evals_complete", msg, time.Time{}); err != nil {
+		return err
+	}
+
 	// We now have all the evals, gather the allocations and placement times.
 
 	// scheduleTime is a map of alloc ID to map of desired status and time.
@@ -289,7 +417,9 @@ ALLOC_POLL:
 		waitTime, exceeded := getSleepTime(cutoff)
 		if !exceeded && !first {
 			log.Printf("[DEBUG] nomad: next eval poll in %s", waitTime)
-			time.Sleep(waitTime)
+			if err := sleep(ctx, waitTime); err != nil {
+				return err
+			}
 		}
 		first = false
 
@@ -366,36 +496,39 @@ ALLOC_POLL:
 		log.Printf("[DEBUG] nomad: alloc id %q failed on client: %v", id, reason)
 	}
 
-	// Print the results.
+	// Emit the results.
 	if l := len(failedEvals); l != 0 {
-		fmt.Fprintf(os.Stdout, "failed_evals|%f\n", float64(l))
-	}
-	for time, count := range accumTimes(failedAllocs) {
-		fmt.Fprintf(os.Stdout, "failed_allocs|%f|%d\n", float64(count), time)
-	}
-	for time, count := range accumTimes(startTimes) {
-		fmt.Fprintf(os.Stdout, "running|%f|%d\n", float64(count), time)
-	}
-	for time, count := range accumTimes(receivedTimes) {
-		fmt.Fprintf(os.Stdout, "received|%f|%d\n", float64(count), time)
-	}
-	for time, count := range accumTimesOn("run", scheduleTimes) {
-		fmt.Fprintf(os.Stdout, "placed_run|%f|%d\n", float64(count), time)
-	}
-	for time, count := range accumTimesOn("failed", scheduleTimes) {
-		fmt.Fprintf(os.Stdout, "placed_failed|%f|%d\n", float64(count), time)
+		if err := w.Update("failed_evals", float64(l), time.Time{}); err != nil {
+			return err
+		}
 	}
-	for time, count := range accumTimesOn("stop", scheduleTimes) {
-		fmt.Fprintf(os.Stdout, "placed_stop|%f|%d\n", float64(count), time)
+	results := []struct {
+		metric string
+		counts map[int64]int64
+	}{
+		{"failed_allocs", accumTimes(failedAllocs)},
+		{"running", accumTimes(startTimes)},
+		{"received", accumTimes(receivedTimes)},
+		{"placed_run", accumTimesOn("run", scheduleTimes)},
+		{"placed_failed", accumTimesOn("failed", scheduleTimes)},
+		{"placed_stop", accumTimesOn("stop", scheduleTimes)},
+	}
+	for _, r := range results {
+		if err := emit(w, r.metric, r.counts); err != nil {
+			return err
+		}
 	}
 
-	// Aggregate eval triggerbys.
+	// Aggregate eval triggerbys, labeling the count of evals by trigger.
 	triggers := make(map[string]int, len(evals))
 	for _, eval := range evals {
 		triggers[eval.TriggeredBy]++
 	}
 	for trigger, count := range triggers {
-		fmt.Fprintf(os.Stdout, "trigger:%s|%f\n", trigger, float64(count))
+		metric := sdk.Labeled("evals", sdk.Labels{"trigger": trigger})
+		if err := w.Update(metric, float64(count), time.Time{}); err != nil {
+			return err
+		}
 	}
 
 	// Print if the scheduler changed scheduling decisions
@@ -426,13 +559,36 @@ ALLOC_POLL:
 		}
 	}
 
-	for flipType, _ := range flips {
-		for time, count := range accumTimesOn(flipType, flips) {
-			fmt.Fprintf(os.Stdout, "%v|%f|%d\n", flipType, float64(count), time)
+	for flipType := range flipTypes {
+		metric := sdk.Labeled("flips", sdk.Labels{"type": flipType})
+		if err := emit(w, metric, accumTimesOn(flipType, flips)); err != nil {
+			return err
 		}
 	}
 
-	return 0
+	return nil
+}
+
+// emit reports the cumulative counts of a metric, keyed by the Unix time in
+// nanoseconds at which they were reached.
+func emit(w sdk.StatusWriter, metric string, counts map[int64]int64) error {
+	for ts, count := range counts {
+		if err := w.Update(metric, float64(count), time.Unix(0, ts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleep waits for the duration, returning early with an error if the
+// context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // getSleepTime takes a cutoff time and returns how long you should sleep
@@ -499,25 +655,25 @@ func accumTimesOn(innerKey string, in map[string]map[string]int64) map[int64]int
 	return accumTimes(converted)
 }
 
-func handleTeardown() int {
+func (b *benchmark) Teardown(ctx context.Context) error {
 	// Get the API client
 	client, err := api.NewClient(api.DefaultConfig())
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed creating nomad client: %v", err)
+		return fmt.Errorf("failed creating nomad client: %v", err)
 	}
 
 	// Iterate all of the jobs and stop them
 	log.Printf("[DEBUG] nomad: deregistering benchmark jobs")
 	jobs, _, err := client.Jobs().List(nil)
 	if err != nil {
-		log.Fatalf("[ERR] nomad: failed listing jobs: %v", err)
+		return fmt.Errorf("failed listing jobs: %v", err)
 	}
 	for _, job := range jobs {
 		if _, _, err := client.Jobs().Deregister(job.ID, nil); err != nil {
-			log.Fatalf("[ERR] nomad: failed deregistering job: %v", err)
+			return fmt.Errorf("failed deregistering job: %v", err)
 		}
 	}
-	return 0
+	return nil
 }
 
 func convertStructJob(in *structs.Job) (*api.Job, error) {
@@ -565,6 +721,9 @@ To run this benchmark:
        JOBSPEC - The path to a valid job definition file
        JOBS    - The number of times to submit the job
 
+     Optionally, REDIS_ADDR may be set to the address of the Redis server
+     the classlogger tasks report to, to validate where tasks were placed.
+
      An example use would look like this:
 
        $ JOBSPEC=./job1.nomad JOBS=100 bench-runner bench-nomad
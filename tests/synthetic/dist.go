@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// distribution samples the start-up latency of a task.
+type distribution interface {
+	sample(r *rand.Rand) time.Duration
+}
+
+// parseDistribution parses a latency distribution of the form
+// "<kind>:<params>", where params is a comma-separated list of Go
+// durations:
+//
+//	constant:<latency>
+//	uniform:<min>,<max>
+//	normal:<mean>,<stddev>
+//	exponential:<mean>
+func parseDistribution(spec string) (distribution, error) {
+	idx := strings.Index(spec, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("latency %q must be of the form <kind>:<params>", spec)
+	}
+	kind := spec[:idx]
+	var params []time.Duration
+	for _, p := range strings.Split(spec[idx+1:], ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameter of latency %q: %v", spec, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("parameters of latency %q must not be negative", spec)
+		}
+		params = append(params, d)
+	}
+
+	want := map[string]int{"constant": 1, "uniform": 2, "normal": 2, "exponential": 1}
+	n, ok := want[kind]
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("unknown latency distribution %q", kind)
+	case len(params) != n:
+		return nil, fmt.Errorf("latency distribution %q takes %d parameter(s)", kind, n)
+	}
+
+	switch kind {
+	case "constant":
+		return constantDist(params[0]), nil
+	case "uniform":
+		if params[1] < params[0] {
+			return nil, fmt.Errorf("maximum of latency %q is less than its minimum", spec)
+		}
+		return &uniformDist{min: params[0], max: params[1]}, nil
+	case "normal":
+		return &normalDist{mean: params[0], stddev: params[1]}, nil
+	}
+	return exponentialDist(params[0]), nil
+}
+
+// constantDist gives every task the same latency.
+type constantDist time.Duration
+
+func (d constantDist) sample(r *rand.Rand) time.Duration {
+	return time.Duration(d)
+}
+
+// uniformDist spreads latencies evenly between min and max.
+type uniformDist struct {
+	min, max time.Duration
+}
+
+func (d *uniformDist) sample(r *rand.Rand) time.Duration {
+	return d.min + time.Duration(r.Float64()*float64(d.max-d.min))
+}
+
+// normalDist clusters latencies around the mean. Samples below zero are
+// clamped to zero.
+type normalDist struct {
+	mean, stddev time.Duration
+}
+
+func (d *normalDist) sample(r *rand.Rand) time.Duration {
+	v := r.NormFloat64()*float64(d.stddev) + float64(d.mean)
+	return time.Duration(math.Max(v, 0))
+}
+
+// exponentialDist gives most tasks a short latency, with a long tail.
+type exponentialDist time.Duration
+
+func (d exponentialDist) sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(d))
+}
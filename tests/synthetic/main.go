@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/schedbench/sdk"
+)
+
+const (
+	// version is the version of this benchmark implementation.
+	version = "0.1.0"
+
+	// defaultLatency is the latency distribution of tasks if none is given.
+	defaultLatency = "uniform:0s,1s"
+
+	// planPollInterval is how often the status step checks whether the run
+	// step has written the plan.
+	planPollInterval = 50 * time.Millisecond
+)
+
+func main() {
+	sdk.Main(&benchmark{})
+}
+
+// benchmark simulates a scheduler starting tasks, without any scheduler.
+// The run step plans when each task starts or fails, and writes the plan to
+// the state directory, where the status step, executing in another
+// process, picks it up and reports the tasks as their start times pass.
+type benchmark struct {
+	// tasks is the number of tasks to start, and latency is the
+	// distribution of the time each takes to start once submitted.
+	tasks   int
+	latency distribution
+
+	// failureRate is the fraction of tasks which fail to start.
+	failureRate float64
+
+	// stall is how long the scheduler stalls once stallAt of the tasks
+	// have started, as a fraction.
+	stall   time.Duration
+	stallAt float64
+
+	// seed seeds the random number generator, so that plans can be
+	// reproduced.
+	seed int64
+
+	// stateDir is the directory the plan is shared between steps in.
+	stateDir string
+
+	// failStep and hangStep name a step which fails, or which blocks until
+	// it is stopped, to exercise how the runner handles them.
+	failStep string
+	hangStep string
+}
+
+// plan is when each task starts or fails, measured from the start of the
+// run step.
+type plan struct {
+	Start time.Time    `json:"start"`
+	Tasks []*taskEvent `json:"tasks"`
+}
+
+// taskEvent is the outcome of a single task.
+type taskEvent struct {
+	Offset time.Duration `json:"offset"`
+	Failed bool          `json:"failed,omitempty"`
+}
+
+func (b *benchmark) Help() string {
+	return usage
+}
+
+func (b *benchmark) Configure() error {
+	var err error
+	b.tasks = 100
+	if os.Getenv("TASKS") != "" {
+		if b.tasks, err = sdk.EnvInt("TASKS"); err != nil {
+			return err
+		}
+		if b.tasks < 0 {
+			return fmt.Errorf("TASKS must not be negative")
+		}
+	}
+
+	latency := os.Getenv("LATENCY")
+	if latency == "" {
+		latency = defaultLatency
+	}
+	if b.latency, err = parseDistribution(latency); err != nil {
+		return err
+	}
+
+	if b.failureRate, err = envFloat("FAILURE_RATE", 0); err != nil {
+		return err
+	}
+	if b.failureRate < 0 || b.failureRate > 1 {
+		return fmt.Errorf("FAILURE_RATE must be between 0 and 1")
+	}
+
+	if b.stall, err = sdk.EnvDuration("STALL", 0); err != nil {
+		return err
+	}
+	if b.stallAt, err = envFloat("STALL_AT", 0.5); err != nil {
+		return err
+	}
+	if b.stallAt < 0 || b.stallAt > 1 {
+		return fmt.Errorf("STALL_AT must be between 0 and 1")
+	}
+
+	b.seed = time.Now().UnixNano()
+	if os.Getenv("SEED") != "" {
+		seed, err := sdk.EnvInt("SEED")
+		if err != nil {
+			return err
+		}
+		b.seed = int64(seed)
+	}
+
+	// The state directory is required rather than defaulted, since a fixed
+	// default would be shared by benchmarks running at the same time.
+	if b.stateDir = os.Getenv("STATE_DIR"); b.stateDir == "" {
+		return fmt.Errorf("STATE_DIR must be set")
+	}
+	b.failStep = os.Getenv("FAIL_STEP")
+	b.hangStep = os.Getenv("HANG_STEP")
+	return nil
+}
+
+// envFloat returns the value of the environment variable as a number, or
+// def if it is unset.
+func envFloat(name string, def float64) (float64, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be numeric", name)
+	}
+	return f, nil
+}
+
+// Info describes the implementation without configuring it. The expected
+// number of tasks is only reported if none fail, since otherwise how many
+// start is left to chance.
+func (b *benchmark) Info(ctx context.Context) (*sdk.Info, error) {
+	out := &sdk.Info{
+		Name:        "synthetic",
+		Version:     version,
+		RequiredEnv: []string{"STATE_DIR"},
+		Metrics:     []string{"running", "failed", "latency_ms"},
+	}
+	if rate, err := envFloat("FAILURE_RATE", 0); err != nil || rate != 0 {
+		return out, nil
+	}
+	if tasks, err := strconv.Atoi(os.Getenv("TASKS")); err == nil {
+		out.ExpectedTasks = tasks
+	} else {
+		out.ExpectedTasks = 100
+	}
+	return out, nil
+}
+
+// step simulates the failure or hang of the step, if requested.
+func (b *benchmark) step(ctx context.Context, phase string) error {
+	switch phase {
+	case b.failStep:
+		return fmt.Errorf("simulated failure")
+	case b.hangStep:
+		log.Printf("[DEBUG] synthetic: %s hanging until stopped", phase)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+// planPath returns the path of the file the plan is shared in.
+func (b *benchmark) planPath() string {
+	return filepath.Join(b.stateDir, "plan.json")
+}
+
+func (b *benchmark) Setup(ctx context.Context) error {
+	if err := b.step(ctx, "setup"); err != nil {
+		return err
+	}
+
+	// Remove the plan of any previous benchmark, so that status waits for
+	// the plan of this one.
+	if err := os.MkdirAll(b.stateDir, 0755); err != nil {
+		return fmt.Errorf("failed creating state directory: %v", err)
+	}
+	if err := os.Remove(b.planPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed removing previous plan: %v", err)
+	}
+	return nil
+}
+
+func (b *benchmark) Run(ctx context.Context) error {
+	start := time.Now()
+	if err := b.step(ctx, "run"); err != nil {
+		return err
+	}
+
+	p := &plan{Start: start, Tasks: make([]*taskEvent, b.tasks)}
+	r := rand.New(rand.NewSource(b.seed))
+	for i := range p.Tasks {
+		p.Tasks[i] = &taskEvent{
+			Offset: b.latency.sample(r),
+			Failed: r.Float64() < b.failureRate,
+		}
+	}
+	sort.Slice(p.Tasks, func(i, j int) bool {
+		return p.Tasks[i].Offset < p.Tasks[j].Offset
+	})
+
+	// Delay every task after the stall point
+	if b.stall > 0 {
+		for _, task := range p.Tasks[int(b.stallAt*float64(len(p.Tasks))):] {
+			task.Offset += b.stall
+		}
+	}
+	log.Printf("[DEBUG] synthetic: planned %d tasks (seed %d)", len(p.Tasks), b.seed)
+
+	// Write the plan atomically, so status never reads it partially
+	out, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	tmp := b.planPath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, out, 0644); err != nil {
+		return fmt.Errorf("failed writing plan: %v", err)
+	}
+	if err := os.Rename(tmp, b.planPath()); err != nil {
+		return fmt.Errorf("failed writing plan: %v", err)
+	}
+	return nil
+}
+
+// readPlan reads the plan written by the run step, returning nil if it has
+// not been written yet.
+func (b *benchmark) readPlan() (*plan, error) {
+	out, err := ioutil.ReadFile(b.planPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed reading plan: %v", err)
+	}
+	p := &plan{}
+	if err := json.Unmarshal(out, p); err != nil {
+		return nil, fmt.Errorf("failed parsing plan: %v", err)
+	}
+	return p, nil
+}
+
+func (b *benchmark) Status(ctx context.Context, w sdk.StatusWriter) error {
+	if err := b.step(ctx, "status"); err != nil {
+		return err
+	}
+
+	// Wait for the run step to plan the tasks
+	var p *plan
+	for {
+		var err error
+		if p, err = b.readPlan(); err != nil {
+			return err
+		}
+		if p != nil {
+			break
+		}
+		if err := sleep(ctx, planPollInterval); err != nil {
+			return err
+		}
+	}
+
+	msg := fmt.Sprintf("%d tasks planned", len(p.Tasks))
+	if err := w.Event("plan", msg, p.Start); err != nil {
+		return err
+	}
+
+	// Report each task as its time passes, marking where the stall begins
+	var running, failed int
+	stallIdx := -1
+	if b.stall > 0 {
+		stallIdx = int(b.stallAt * float64(len(p.Tasks)))
+	}
+	for i, task := range p.Tasks {
+		at := p.Start.Add(task.Offset)
+		if err := sleep(ctx, time.Until(at)); err != nil {
+			return err
+		}
+		if i == stallIdx {
+			msg := fmt.Sprintf("remaining tasks stalled for %s", b.stall)
+			if err := w.Event("stall", msg, at.Add(-b.stall)); err != nil {
+				return err
+			}
+		}
+		metric, value := "running", &running
+		if task.Failed {
+			metric, value = "failed", &failed
+		}
+		*value++
+		if err := w.Update(metric, float64(*value), at); err != nil {
+			return err
+		}
+
+		// Report how long each task which started took to do so
+		if !task.Failed {
+			latency := float64(task.Offset) / float64(time.Millisecond)
+			if err := w.UpdateKind("latency_ms", sdk.KindSample, latency, at); err != nil {
+				return err
+			}
+		}
+	}
+	log.Printf("[DEBUG] synthetic: %d tasks running, %d failed", running, failed)
+	return nil
+}
+
+func (b *benchmark) Validate(ctx context.Context) (string, error) {
+	if err := b.step(ctx, "validate"); err != nil {
+		return "", err
+	}
+	p, err := b.readPlan()
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", fmt.Errorf("no tasks were planned")
+	}
+
+	var failed int
+	for _, task := range p.Tasks {
+		if task.Failed {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d of %d tasks started, %d failed\n",
+		len(p.Tasks)-failed, len(p.Tasks), failed), nil
+}
+
+func (b *benchmark) Teardown(ctx context.Context) error {
+	if err := b.step(ctx, "teardown"); err != nil {
+		return err
+	}
+	if err := os.Remove(b.planPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed removing plan: %v", err)
+	}
+	return nil
+}
+
+// sleep waits for the duration, returning early with an error if the
+// context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+const usage = `
+NOTICE: This is a benchmark implementation binary and is not intended to be
+run directly. The full path to this binary should be passed to bench-runner.
+
+This benchmark simulates a scheduler starting tasks, so that the runner and
+the analysis of its results can be exercised without a real scheduler. The
+run step plans when each task starts, and the status step reports the tasks
+as they start, along with a sample of the latency_ms distribution for each.
+
+The plan is shared between steps in the directory given by the required
+STATE_DIR environment variable, which must not be shared with any other
+benchmark running at the same time. The simulation is configured by the
+following optional environment variables:
+
+  TASKS        - The number of tasks to start. Defaults to 100.
+  LATENCY      - The distribution of the time each task takes to start, one
+                 of constant:<d>, uniform:<min>,<max>, normal:<mean>,<stddev>
+                 or exponential:<mean>. Defaults to uniform:0s,1s.
+  FAILURE_RATE - The fraction of tasks which fail to start, reported by the
+                 failed metric. Defaults to 0. If set, the number of tasks
+                 expected to start is not reported to the runner.
+  STALL        - How long the scheduler stalls part way through, such as 5s.
+  STALL_AT     - The fraction of tasks started before the stall. Defaults
+                 to 0.5.
+  SEED         - Seeds the simulation, so that it can be reproduced.
+  FAIL_STEP    - The name of a step which fails, such as run.
+  HANG_STEP    - The name of a step which runs until it is stopped.
+
+An example use would look like this:
+
+  $ STATE_DIR=$(mktemp -d) TASKS=1000 LATENCY=normal:2s,500ms \
+      bench-runner bench-synthetic
+`